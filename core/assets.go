@@ -5,6 +5,7 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"path/filepath"
 	"slices"
 	"strings"
 )
@@ -176,15 +177,31 @@ func (uexp *Uexp) UpdateWithNewUexp(newUexp *Uexp) {
 	}
 }
 
+// Make a deep copy of entries
+func (uexp *Uexp) Clone() *Uexp {
+	newUexp := &Uexp{
+		head:    uexp.head,
+		Lang:    uexp.Lang,
+		noneId:  uexp.noneId,
+		Entries: make([]Entry, len(uexp.Entries)),
+	}
+	for i := range len(uexp.Entries) {
+		e := uexp.Entries[i]
+		e.SubEntries = slices.Clone(e.SubEntries)
+		newUexp.Entries[i] = e
+	}
+	return newUexp
+}
+
 func (uexp *Uexp) Print(verbose ...bool) {
-	fmt.Printf("lang: %s\n", uexp.Lang)
+	Printf("lang: %s\n", uexp.Lang)
 	entryCount := len(uexp.Entries)
-	fmt.Printf("entry count: %d\n", entryCount)
+	Printf("entry count: %d\n", entryCount)
 	if len(verbose) == 0 || !verbose[0] {
 		return
 	}
 	if entryCount > 0 {
-		fmt.Println("entries:")
+		Println("entries:")
 	}
 	for i := range len(uexp.Entries) {
 		uexp.Entries[i].Print()
@@ -199,8 +216,8 @@ func (uexp *Uexp) ReadFromCsv(r *csv.Reader) {
 			break
 		} else if err != nil {
 			Throw(err)
-		} else if len(row) != 3 {
-			Throw("each row should has 3 items in csv")
+		} else if len(row) != 3 && len(row) != 4 {
+			Throw("each row should has 3 or 4 items in csv")
 		}
 		id := row[0]
 		if id == "id" {
@@ -222,17 +239,68 @@ func (uexp *Uexp) ReadFromCsv(r *csv.Reader) {
 	}
 }
 
+// Build entries from csv without the original asset
+func (uexp *Uexp) ReadEntriesFromCsv(r *csv.Reader) {
+	uexp.Entries = []Entry{}
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			Throw(err)
+		} else if len(row) != 3 && len(row) != 4 {
+			Throw("each row should has 3 or 4 items in csv")
+		}
+		id := row[0]
+		if id == "id" {
+			continue // first row
+		} else if id == "language" {
+			uexp.Lang = row[2]
+			continue
+		}
+		entryCount := len(uexp.Entries)
+		if row[1] == "" || entryCount == 0 || uexp.Entries[entryCount-1].Id != id {
+			uexp.Entries = append(uexp.Entries, Entry{Id: id, SubEntries: []SubEntry{}})
+			entryCount++
+		}
+		e := &uexp.Entries[entryCount-1]
+		if row[1] == "" {
+			e.Text = CsvStrToGoStr(row[2])
+			e.MachineTranslated = len(row) > 3 && row[3] == "1"
+		} else {
+			e.SubEntries = append(e.SubEntries, SubEntry{Id: row[1], Text: CsvStrToGoStr(row[2])})
+		}
+	}
+}
+
+func (uexp *Uexp) HasMachineTranslation() bool {
+	for i := range len(uexp.Entries) {
+		if uexp.Entries[i].MachineTranslated {
+			return true
+		}
+	}
+	return false
+}
+
+// The machine_translated column is added when some entries are machine-translated.
 func (uexp *Uexp) WriteAsCsv(w *csv.Writer) {
+	withFlag := uexp.HasMachineTranslation()
 	record := []string{"id", "sub_id", "text"}
+	if withFlag {
+		record = append(record, "machine_translated")
+	}
 	if err := w.Write(record); err != nil {
 		Throw(err)
 	}
 	record = []string{"language", "", uexp.Lang}
+	if withFlag {
+		record = append(record, "")
+	}
 	if err := w.Write(record); err != nil {
 		Throw(err)
 	}
 	for i := range len(uexp.Entries) {
-		uexp.Entries[i].WriteAsCsv(w)
+		uexp.Entries[i].WriteAsCsv(w, withFlag)
 	}
 }
 
@@ -305,10 +373,10 @@ func (uasset *Uasset) Update() {
 
 func (uasset *Uasset) Print(verbose ...bool) {
 	nameCount := len(uasset.Names)
-	fmt.Printf("name count: %d\n", nameCount)
+	Printf("name count: %d\n", nameCount)
 	if len(verbose) != 0 && verbose[0] {
 		for i := range len(uasset.Names) {
-			fmt.Printf("  %s\n", uasset.Names[i])
+			Printf("  %s\n", uasset.Names[i])
 		}
 	}
 	uasset.Uexp.Print(verbose[0])
@@ -320,7 +388,7 @@ func (uasset *Uasset) ReadFromFile(filePath string) {
 	serializer := NewSerializer()
 
 	// Open a read only file
-	fmt.Printf("Reading %s...\n", filePath)
+	Printf("Reading %s...\n", filePath)
 	uassetFile := OpenFile(filePath)
 	defer uassetFile.Close()
 
@@ -330,7 +398,7 @@ func (uasset *Uasset) ReadFromFile(filePath string) {
 	if uasset.Ver == VER_FF7R {
 		// Read .uexp
 		uexpPath := RemoveExtension(filePath) + ".uexp"
-		fmt.Printf("Reading %s...\n", uexpPath)
+		Printf("Reading %s...\n", uexpPath)
 		uexpFile := OpenFile(uexpPath)
 		defer uexpFile.Close()
 		serializer.SetReadFile(uexpFile)
@@ -341,13 +409,36 @@ func (uasset *Uasset) ReadFromFile(filePath string) {
 	uasset.Uexp = uexp
 }
 
+// Read text data from .uasset, .csv or .json
+func LoadUexpFromFile(filePath string) *Uexp {
+	ext := filepath.Ext(filePath)
+	if ext == ".uasset" {
+		uasset := Uasset{}
+		uasset.ReadFromFile(filePath)
+		return uasset.Uexp
+	}
+
+	uexp := &Uexp{}
+	if ext == ".csv" {
+		Printf("Reading %s...\n", filePath)
+		file := OpenFile(filePath)
+		defer file.Close()
+		uexp.ReadEntriesFromCsv(csv.NewReader(file))
+	} else if ext == ".json" {
+		LoadFromJson(filePath, uexp)
+	} else {
+		Throw(fmt.Errorf("unsupported file type. (%s)", filePath))
+	}
+	return uexp
+}
+
 func (uasset *Uasset) WriteToFile(filePath string) {
 	uasset.Update()
 
 	serializer := NewSerializer()
 
 	// Open or create a file
-	fmt.Printf("Writing %s...\n", filePath)
+	Printf("Writing %s...\n", filePath)
 	uassetFile := CreateFile(filePath)
 	defer uassetFile.Close()
 
@@ -358,7 +449,7 @@ func (uasset *Uasset) WriteToFile(filePath string) {
 	if uasset.Ver == VER_FF7R {
 		// Read .uexp
 		uexpPath := RemoveExtension(filePath) + ".uexp"
-		fmt.Printf("Writing %s...\n", uexpPath)
+		Printf("Writing %s...\n", uexpPath)
 		uexpFile := CreateFile(uexpPath)
 		defer uexpFile.Close()
 		serializer.SetWriteFile(uexpFile)
@@ -0,0 +1,224 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Characters used by each language
+type CharsetReport struct {
+	mutex  sync.Mutex
+	langs  []string // empty means all languages
+	counts map[string]map[rune]int
+}
+
+func NewCharsetReport(langs []string) *CharsetReport {
+	return &CharsetReport{langs: langs, counts: map[string]map[rune]int{}}
+}
+
+func countRunes(text string, counts map[rune]int) {
+	for _, t := range Tokenize(text) {
+		if t.Kind != TOKEN_TEXT && t.Kind != TOKEN_MALFORMED {
+			continue
+		}
+		for _, c := range t.Raw {
+			if needsGlyph(c) {
+				counts[c]++
+			}
+		}
+	}
+}
+
+// Collect characters in texts and sub entries
+func (r *CharsetReport) Add(uexp *Uexp) bool {
+	if len(r.langs) > 0 && !slices.Contains(r.langs, uexp.Lang) {
+		return false
+	}
+	counts := map[rune]int{}
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		if e.Text == e.Id {
+			continue
+		}
+		countRunes(e.Text, counts)
+		for _, se := range e.SubEntries {
+			countRunes(se.Text, counts)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	total, ok := r.counts[uexp.Lang]
+	if !ok {
+		total = map[rune]int{}
+		r.counts[uexp.Lang] = total
+	}
+	for c, count := range counts {
+		total[c] += count
+	}
+	return true
+}
+
+func (r *CharsetReport) GetLangs() []string {
+	langs := make([]string, 0, len(r.counts))
+	for lang := range r.counts {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+// Get characters of a language sorted by code point
+func (r *CharsetReport) GetRunes(lang string) []rune {
+	runes := make([]rune, 0, len(r.counts[lang]))
+	for c := range r.counts[lang] {
+		runes = append(runes, c)
+	}
+	slices.Sort(runes)
+	return runes
+}
+
+// Merge sorted characters into ranges of consecutive code points
+func ToRanges(runes []rune) [][2]rune {
+	ranges := [][2]rune{}
+	for _, c := range runes {
+		if len(ranges) > 0 && ranges[len(ranges)-1][1]+1 == c {
+			ranges[len(ranges)-1][1] = c
+			continue
+		}
+		ranges = append(ranges, [2]rune{c, c})
+	}
+	return ranges
+}
+
+// Format a range as U+0020-007E (the same as unicode-range of CSS)
+func FormatRange(rng [2]rune) string {
+	if rng[0] == rng[1] {
+		return fmt.Sprintf("U+%04X", rng[0])
+	}
+	return fmt.Sprintf("U+%04X-%04X", rng[0], rng[1])
+}
+
+func (r *CharsetReport) writeFrequency(w *csv.Writer, lang string) {
+	runes := r.GetRunes(lang)
+	counts := r.counts[lang]
+	sort.SliceStable(runes, func(i, j int) bool {
+		return counts[runes[i]] > counts[runes[j]]
+	})
+	w.Write([]string{"char", "code", "count"})
+	for _, c := range runes {
+		w.Write([]string{string(c), fmt.Sprintf("U+%04X", c), strconv.Itoa(counts[c])})
+	}
+}
+
+func writeCharsetFile(filePath string, write func(w io.Writer)) {
+	Printf("Writing %s...\n", filePath)
+	file := CreateFile(filePath)
+	defer file.Close()
+	write(file)
+}
+
+// Write charset_<LANG>.txt (characters), charset_<LANG>_ranges.txt and charset_<LANG>_freq.csv
+func (r *CharsetReport) SaveFiles(outdir string) {
+	for _, lang := range r.GetLangs() {
+		runes := r.GetRunes(lang)
+		base := filepath.Join(outdir, "charset_"+lang)
+		writeCharsetFile(base+".txt", func(w io.Writer) {
+			fmt.Fprintln(w, string(runes))
+		})
+		writeCharsetFile(base+"_ranges.txt", func(w io.Writer) {
+			for _, rng := range ToRanges(runes) {
+				fmt.Fprintln(w, FormatRange(rng))
+			}
+		})
+		writeCharsetFile(base+"_freq.csv", func(w io.Writer) {
+			writer := csv.NewWriter(w)
+			r.writeFrequency(writer, lang)
+			writer.Flush()
+			if err := writer.Error(); err != nil {
+				Throw(err)
+			}
+		})
+	}
+}
+
+// Characters added or removed from a previous charset
+type CharsetDiff struct {
+	Lang    string `json:"language"`
+	Added   string `json:"added"`
+	Removed string `json:"removed"`
+}
+
+// Load characters from a text file. Line breaks are ignored.
+func LoadCharset(filePath string) map[rune]bool {
+	Printf("Reading %s...\n", filePath)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		Throw(err)
+	}
+	runes := map[rune]bool{}
+	for _, c := range strings.TrimPrefix(string(data), "\uFEFF") {
+		if c != '\r' && c != '\n' {
+			runes[c] = true
+		}
+	}
+	return runes
+}
+
+// Compare characters with a previous charset.
+// basePath is a charset file, or a directory that has charset_<LANG>.txt.
+func (r *CharsetReport) Diff(basePath string) []CharsetDiff {
+	diffs := []CharsetDiff{}
+	for _, lang := range r.GetLangs() {
+		path := basePath
+		if PathIsDir(basePath) {
+			path = filepath.Join(basePath, "charset_"+lang+".txt")
+			if !PathExists(path) {
+				Warn("previous charset not found. (%s)", path)
+				continue
+			}
+		}
+		base := LoadCharset(path)
+		added, removed := []rune{}, []rune{}
+		for _, c := range r.GetRunes(lang) {
+			if !base[c] {
+				added = append(added, c)
+			}
+		}
+		for c := range base {
+			if _, ok := r.counts[lang][c]; !ok {
+				removed = append(removed, c)
+			}
+		}
+		slices.Sort(removed)
+		diffs = append(diffs, CharsetDiff{Lang: lang, Added: string(added), Removed: string(removed)})
+	}
+	return diffs
+}
+
+func (r *CharsetReport) WriteAsText(w io.Writer) {
+	for _, lang := range r.GetLangs() {
+		runes := r.GetRunes(lang)
+		fmt.Fprintf(w, "%s: %d characters, %d ranges\n", lang, len(runes), len(ToRanges(runes)))
+	}
+}
+
+func WriteCharsetDiffAsText(w io.Writer, diffs []CharsetDiff) {
+	for _, d := range diffs {
+		fmt.Fprintf(w, "%s: %d added, %d removed\n", d.Lang, len([]rune(d.Added)), len([]rune(d.Removed)))
+		if d.Added != "" {
+			fmt.Fprintf(w, "  + %s\n", d.Added)
+		}
+		if d.Removed != "" {
+			fmt.Fprintf(w, "  - %s\n", d.Removed)
+		}
+	}
+}
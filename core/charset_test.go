@@ -0,0 +1,76 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestToRanges(t *testing.T) {
+	tests := []struct {
+		runes  []rune
+		ranges []string
+	}{
+		{[]rune("abcx"), []string{"U+0061-0063", "U+0078"}},
+		{[]rune("あいう"), []string{"U+3042", "U+3044", "U+3046"}},
+		{[]rune{0x20B9F}, []string{"U+20B9F"}},
+		{[]rune{}, []string{}},
+	}
+	for _, test := range tests {
+		ranges := []string{}
+		for _, rng := range ToRanges(test.runes) {
+			ranges = append(ranges, FormatRange(rng))
+		}
+		if !slices.Equal(ranges, test.ranges) {
+			t.Errorf("ToRanges(%q): got %v, want %v", string(test.runes), ranges, test.ranges)
+		}
+	}
+}
+
+func TestCharsetReportAdd(t *testing.T) {
+	report := NewCharsetReport([]string{"JP"})
+	uexp := &Uexp{Lang: "JP", Entries: []Entry{
+		{Id: "a", Text: "<Red>あい</> {0}\r\nあ", SubEntries: []SubEntry{{Id: "ACTOR", Text: "う"}}},
+		{Id: "ID_ONLY", Text: "ID_ONLY"}, // text is id
+	}}
+	if !report.Add(uexp) {
+		t.Fatalf("Add: JP should be added")
+	}
+	if report.Add(&Uexp{Lang: "US", Entries: []Entry{{Id: "a", Text: "b"}}}) {
+		t.Errorf("Add: US should be skipped")
+	}
+
+	// Tags, placeholders and line breaks are not characters
+	if runes := string(report.GetRunes("JP")); runes != " あいう" {
+		t.Errorf("GetRunes: got %q", runes)
+	}
+	if count := report.counts["JP"]['あ']; count != 2 {
+		t.Errorf("count of あ: got %d, want 2", count)
+	}
+	if langs := report.GetLangs(); !slices.Equal(langs, []string{"JP"}) {
+		t.Errorf("GetLangs: got %v", langs)
+	}
+}
+
+func TestCharsetDiff(t *testing.T) {
+	report := NewCharsetReport(nil)
+	report.Add(&Uexp{Lang: "JP", Entries: []Entry{{Id: "a", Text: "あいう"}}})
+	report.Add(&Uexp{Lang: "KR", Entries: []Entry{{Id: "a", Text: "가"}}})
+
+	// Previous charsets have the same names as SaveFiles writes. KR has no previous charset.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "charset_JP.txt"), []byte("\uFEFFあえ\r\nい\r\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	diffs := report.Diff(dir)
+	if len(diffs) != 1 || diffs[0] != (CharsetDiff{Lang: "JP", Added: "う", Removed: "え"}) {
+		t.Errorf("Diff: got %+v", diffs)
+	}
+
+	// A file is used for all languages
+	diffs = report.Diff(filepath.Join(dir, "charset_JP.txt"))
+	if len(diffs) != 2 || diffs[1] != (CharsetDiff{Lang: "KR", Added: "가", Removed: "あいえ"}) {
+		t.Errorf("Diff: got %+v", diffs)
+	}
+}
@@ -0,0 +1,135 @@
+package core
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// CityHash64 (v1.1) that Unreal Engine uses for hashes of object paths
+
+const (
+	cityK0  = 0xc3a5c85c97cb3127
+	cityK1  = 0xb492b66fbe98f273
+	cityK2  = 0x9ae16a3b2f90404f
+	cityMul = 0x9ddfea08eb382d69
+)
+
+func cityFetch64(s []byte) uint64 {
+	return binary.LittleEndian.Uint64(s)
+}
+
+func cityFetch32(s []byte) uint64 {
+	return uint64(binary.LittleEndian.Uint32(s))
+}
+
+func cityShiftMix(v uint64) uint64 {
+	return v ^ (v >> 47)
+}
+
+func cityHashLen16(u uint64, v uint64, mul uint64) uint64 {
+	a := (u ^ v) * mul
+	a ^= a >> 47
+	b := (v ^ a) * mul
+	b ^= b >> 47
+	return b * mul
+}
+
+func cityHashLen0to16(s []byte) uint64 {
+	n := uint64(len(s))
+	if n >= 8 {
+		mul := cityK2 + n*2
+		a := cityFetch64(s) + cityK2
+		b := cityFetch64(s[n-8:])
+		c := bits.RotateLeft64(b, -37)*mul + a
+		d := (bits.RotateLeft64(a, -25) + b) * mul
+		return cityHashLen16(c, d, mul)
+	}
+	if n >= 4 {
+		mul := cityK2 + n*2
+		a := cityFetch32(s)
+		return cityHashLen16(n+(a<<3), cityFetch32(s[n-4:]), mul)
+	}
+	if n > 0 {
+		a, b, c := uint32(s[0]), uint32(s[n>>1]), uint32(s[n-1])
+		y := a + b<<8
+		z := uint32(n) + c<<2
+		return cityShiftMix(uint64(y)*cityK2^uint64(z)*cityK0) * cityK2
+	}
+	return cityK2
+}
+
+func cityHashLen17to32(s []byte) uint64 {
+	n := uint64(len(s))
+	mul := cityK2 + n*2
+	a := cityFetch64(s) * cityK1
+	b := cityFetch64(s[8:])
+	c := cityFetch64(s[n-8:]) * mul
+	d := cityFetch64(s[n-16:]) * cityK2
+	return cityHashLen16(bits.RotateLeft64(a+b, -43)+bits.RotateLeft64(c, -30)+d, a+bits.RotateLeft64(b+cityK2, -18)+c, mul)
+}
+
+func cityHashLen33to64(s []byte) uint64 {
+	n := uint64(len(s))
+	mul := cityK2 + n*2
+	a := cityFetch64(s) * cityK2
+	b := cityFetch64(s[8:])
+	c := cityFetch64(s[n-24:])
+	d := cityFetch64(s[n-32:])
+	e := cityFetch64(s[16:]) * cityK2
+	f := cityFetch64(s[24:]) * 9
+	g := cityFetch64(s[n-8:])
+	h := cityFetch64(s[n-16:]) * mul
+	u := bits.RotateLeft64(a+g, -43) + (bits.RotateLeft64(b, -30)+c)*9
+	v := ((a + g) ^ d) + f + 1
+	w := bits.ReverseBytes64((u+v)*mul) + h
+	x := bits.RotateLeft64(e+f, -42) + c
+	y := (bits.ReverseBytes64((v+w)*mul) + g) * mul
+	z := e + f + c
+	a = bits.ReverseBytes64((x+z)*mul+y) + b
+	b = cityShiftMix((z+a)*mul+d+h) * mul
+	return b + x
+}
+
+func cityWeakHashLen32WithSeeds(s []byte, a uint64, b uint64) (uint64, uint64) {
+	w, x, y, z := cityFetch64(s), cityFetch64(s[8:]), cityFetch64(s[16:]), cityFetch64(s[24:])
+	a += w
+	b = bits.RotateLeft64(b+a+z, -21)
+	c := a
+	a += x
+	a += y
+	b += bits.RotateLeft64(a, -44)
+	return a + z, b + c
+}
+
+func CityHash64(s []byte) uint64 {
+	n := uint64(len(s))
+	if n <= 16 {
+		return cityHashLen0to16(s)
+	} else if n <= 32 {
+		return cityHashLen17to32(s)
+	} else if n <= 64 {
+		return cityHashLen33to64(s)
+	}
+
+	// Hash 64 bytes at a time
+	x := cityFetch64(s[n-40:])
+	y := cityFetch64(s[n-16:]) + cityFetch64(s[n-56:])
+	z := cityHashLen16(cityFetch64(s[n-48:])+n, cityFetch64(s[n-24:]), cityMul)
+	v1, v2 := cityWeakHashLen32WithSeeds(s[n-64:], n, z)
+	w1, w2 := cityWeakHashLen32WithSeeds(s[n-32:], y+cityK1, x)
+	x = x*cityK1 + cityFetch64(s)
+
+	n = (n - 1) &^ 63
+	for ; n != 0; n -= 64 {
+		x = bits.RotateLeft64(x+y+v1+cityFetch64(s[8:]), -37) * cityK1
+		y = bits.RotateLeft64(y+v2+cityFetch64(s[48:]), -42) * cityK1
+		x ^= w2
+		y += v1 + cityFetch64(s[40:])
+		z = bits.RotateLeft64(z+w1, -33) * cityK1
+		v1, v2 = cityWeakHashLen32WithSeeds(s, v2*cityK1, x+w1)
+		w1, w2 = cityWeakHashLen32WithSeeds(s[32:], z+w2, y+cityFetch64(s[16:]))
+		z, x = x, z
+		s = s[64:]
+	}
+	return cityHashLen16(cityHashLen16(v1, w1, cityMul)+cityShiftMix(y)*cityK1+z, cityHashLen16(v2, w2, cityMul)+x, cityMul)
+}
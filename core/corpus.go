@@ -0,0 +1,394 @@
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const CORPUS_VERSION = 2
+
+type CorpusAsset struct {
+	Hash    string  `json:"hash"` // sha256 of .uasset and .uexp
+	Size    int64   `json:"size"`
+	ModTime int64   `json:"mod_time"`
+	Lang    string  `json:"language"`
+	Entries []Entry `json:"entries"`
+}
+
+type CorpusRef struct {
+	Path string `json:"path"`
+	Id   string `json:"id"`
+}
+
+type CorpusHit struct {
+	Path  string `json:"path"`
+	Lang  string `json:"language"`
+	Entry *Entry `json:"entry"`
+}
+
+// On-disk index of text assets.
+// It can answer id and text queries without reading assets.
+type Corpus struct {
+	mutex   sync.Mutex
+	seen    map[string]bool                   // assets checked by UpdateAsset
+	Version int                               `json:"version"`
+	Root    string                            `json:"root"`   // absolute path that asset paths are relative to
+	Assets  map[string]*CorpusAsset           `json:"assets"` // relative path -> asset
+	Ids     map[string][]string               `json:"ids"`    // entry id -> relative paths
+	Words   map[string]map[string][]CorpusRef `json:"words"`  // language -> word -> entries
+}
+
+func NewCorpus() *Corpus {
+	return &Corpus{
+		seen:    map[string]bool{},
+		Version: CORPUS_VERSION,
+		Assets:  map[string]*CorpusAsset{},
+		Ids:     map[string][]string{},
+		Words:   map[string]map[string][]CorpusRef{},
+	}
+}
+
+// Load an index file. It returns an empty corpus when the file does not exist.
+func LoadCorpus(filePath string) *Corpus {
+	corpus := NewCorpus()
+	if !PathExists(filePath) {
+		return corpus
+	}
+	LoadFromJson(filePath, corpus)
+	if corpus.Version != CORPUS_VERSION || corpus.Assets == nil {
+		// Rebuild index made by other versions
+		return NewCorpus()
+	}
+	return corpus
+}
+
+func (c *Corpus) Save(filePath string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	SaveAsJson(filePath, c)
+}
+
+func hashFiles(paths ...string) string {
+	h := sha256.New()
+	for _, path := range paths {
+		file := OpenFile(path)
+		_, err := io.Copy(h, file)
+		file.Close()
+		if err != nil {
+			Throw(err)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func isUnder(path string, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// Add an input path of index mode.
+// The root becomes a common parent of the old root and the input path,
+// so assets indexed from other directories keep their entries.
+func (c *Corpus) AddInput(inputPath string) {
+	dir := inputPath
+	if !PathIsDir(dir) {
+		dir = filepath.Dir(dir)
+	}
+	if c.Root == "" {
+		c.Root = dir
+		return
+	}
+	root := c.Root
+	for !isUnder(dir, root) {
+		parent := filepath.Dir(root)
+		if parent == root {
+			Throw(fmt.Errorf("input path does not share a root with index (%s, %s)", inputPath, c.Root))
+		}
+		root = parent
+	}
+	if root == c.Root {
+		return
+	}
+	assets := map[string]*CorpusAsset{}
+	for path, asset := range c.Assets {
+		rel, err := filepath.Rel(root, filepath.Join(c.Root, filepath.FromSlash(path)))
+		if err != nil {
+			Throw(err)
+		}
+		assets[filepath.ToSlash(rel)] = asset
+	}
+	c.Assets = assets
+	c.Root = root
+}
+
+// Get the path of an asset in index
+func (c *Corpus) RelPath(filePath string) string {
+	rel, err := filepath.Rel(c.Root, filePath)
+	if err != nil {
+		Throw(err)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// Add or update an asset. It returns false when the asset is not changed.
+func (c *Corpus) UpdateAsset(filePath string, relPath string) bool {
+	paths := []string{filePath}
+	uexpPath := RemoveExtension(filePath) + ".uexp"
+	if PathExists(uexpPath) {
+		paths = append(paths, uexpPath)
+	}
+	var size int64 = 0
+	var modTime int64 = 0
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			Throw(err)
+		}
+		size += info.Size()
+		modTime = max(modTime, info.ModTime().UnixNano())
+	}
+
+	c.mutex.Lock()
+	c.seen[relPath] = true
+	old, ok := c.Assets[relPath]
+	c.mutex.Unlock()
+	if ok && old.Size == size && old.ModTime == modTime {
+		return false
+	}
+
+	hash := hashFiles(paths...)
+	if ok && old.Hash == hash {
+		c.mutex.Lock()
+		old.ModTime = modTime
+		c.mutex.Unlock()
+		return false
+	}
+
+	uasset := Uasset{}
+	uasset.ReadFromFile(filePath)
+	asset := &CorpusAsset{
+		Hash:    hash,
+		Size:    size,
+		ModTime: modTime,
+		Lang:    uasset.Uexp.Lang,
+		Entries: uasset.Uexp.Entries,
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Assets[relPath] = asset
+	return true
+}
+
+// Remove assets under the input path that are not updated after loading the index.
+// Assets out of the input path are kept. It returns the number of removed assets.
+func (c *Corpus) RemoveMissingAssets(inputPath string) int {
+	prefix := c.RelPath(inputPath)
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	count := 0
+	for path := range c.Assets {
+		if prefix != "." && path != prefix && !strings.HasPrefix(path, prefix+"/") {
+			continue
+		}
+		if !c.seen[path] {
+			delete(c.Assets, path)
+			count++
+		}
+	}
+	return count
+}
+
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Split text into words for the inverted index.
+// Latin words are lower-cased. CJK runs are split into bigrams.
+func SplitWords(text string) []string {
+	words := []string{}
+	for _, t := range Tokenize(text) {
+		if t.Kind != TOKEN_TEXT {
+			continue
+		}
+		runes := []rune(t.Raw)
+		for i := 0; i < len(runes); {
+			r := runes[i]
+			if isCJK(r) {
+				j := i
+				for j < len(runes) && isCJK(runes[j]) {
+					j++
+				}
+				if j-i == 1 {
+					words = append(words, string(runes[i]))
+				}
+				for k := i; k+1 < j; k++ {
+					words = append(words, string(runes[k:k+2]))
+				}
+				i = j
+			} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				j := i
+				for j < len(runes) && !isCJK(runes[j]) && (unicode.IsLetter(runes[j]) || unicode.IsDigit(runes[j])) {
+					j++
+				}
+				words = append(words, strings.ToLower(string(runes[i:j])))
+				i = j
+			} else {
+				i++
+			}
+		}
+	}
+	return words
+}
+
+// Rebuild the id map and the inverted index from assets
+func (c *Corpus) Rebuild() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.Ids = map[string][]string{}
+	c.Words = map[string]map[string][]CorpusRef{}
+
+	paths := make([]string, 0, len(c.Assets))
+	for path := range c.Assets {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		asset := c.Assets[path]
+		words, ok := c.Words[asset.Lang]
+		if !ok {
+			words = map[string][]CorpusRef{}
+			c.Words[asset.Lang] = words
+		}
+		for i := range len(asset.Entries) {
+			e := &asset.Entries[i]
+			c.Ids[e.Id] = append(c.Ids[e.Id], path)
+			ref := CorpusRef{Path: path, Id: e.Id}
+			texts := []string{e.Text}
+			for _, se := range e.SubEntries {
+				texts = append(texts, se.Text)
+			}
+			added := map[string]bool{}
+			for _, text := range texts {
+				for _, word := range SplitWords(text) {
+					if added[word] {
+						continue
+					}
+					added[word] = true
+					words[word] = append(words[word], ref)
+				}
+			}
+		}
+	}
+}
+
+func (c *Corpus) getEntry(path string, id string) *Entry {
+	asset, ok := c.Assets[path]
+	if !ok {
+		return nil
+	}
+	uexp := Uexp{Entries: asset.Entries}
+	i := uexp.FindEntry(id, len(asset.Entries)/2)
+	if i < 0 {
+		return nil
+	}
+	return &asset.Entries[i]
+}
+
+// Find entries by id
+func (c *Corpus) FindId(id string) []CorpusHit {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	hits := []CorpusHit{}
+	for _, path := range c.Ids[id] {
+		e := c.getEntry(path, id)
+		if e != nil {
+			hits = append(hits, CorpusHit{Path: path, Lang: c.Assets[path].Lang, Entry: e})
+		}
+	}
+	return hits
+}
+
+func entryContains(e *Entry, query string) bool {
+	if strings.Contains(strings.ToLower(e.Text), query) {
+		return true
+	}
+	for _, se := range e.SubEntries {
+		if strings.Contains(strings.ToLower(se.Text), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// Find entries that contain the query (case-insensitive).
+// Latin words in the query should be whole words.
+// langs filters languages. Empty means all languages.
+func (c *Corpus) FindText(query string, langs []string) []CorpusHit {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	lowerQuery := strings.ToLower(query)
+	// Single CJK characters are not indexed except for isolated ones.
+	queryWords := slices.DeleteFunc(SplitWords(query), func(word string) bool {
+		runes := []rune(word)
+		return len(runes) == 1 && isCJK(runes[0])
+	})
+	hits := []CorpusHit{}
+
+	for lang, words := range c.Words {
+		if len(langs) > 0 && !slices.Contains(langs, lang) {
+			continue
+		}
+
+		var refs []CorpusRef
+		if len(queryWords) == 0 {
+			// Nothing to look up. Check all entries.
+			refs = []CorpusRef{}
+			for path, asset := range c.Assets {
+				if asset.Lang != lang {
+					continue
+				}
+				for i := range len(asset.Entries) {
+					refs = append(refs, CorpusRef{Path: path, Id: asset.Entries[i].Id})
+				}
+			}
+		} else {
+			// Get entries that have all words
+			refs = words[queryWords[0]]
+			for _, word := range queryWords[1:] {
+				postings := map[CorpusRef]bool{}
+				for _, ref := range words[word] {
+					postings[ref] = true
+				}
+				refs = slices.DeleteFunc(slices.Clone(refs), func(ref CorpusRef) bool {
+					return !postings[ref]
+				})
+			}
+		}
+
+		for _, ref := range refs {
+			e := c.getEntry(ref.Path, ref.Id)
+			if e != nil && entryContains(e, lowerQuery) {
+				hits = append(hits, CorpusHit{Path: ref.Path, Lang: lang, Entry: e})
+			}
+		}
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Path != hits[j].Path {
+			return hits[i].Path < hits[j].Path
+		}
+		return hits[i].Entry.Id < hits[j].Entry.Id
+	})
+	return hits
+}
@@ -0,0 +1,49 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCorpusRoot(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"US/Text", "JP/Text"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	c := NewCorpus()
+	c.AddInput(filepath.Join(root, "US"))
+	c.Assets["Text/Story_TxtRes.uasset"] = &CorpusAsset{Lang: "US"}
+
+	// Paths are kept relative to a common root
+	c.AddInput(filepath.Join(root, "JP"))
+	if c.Root != root {
+		t.Errorf("AddInput: got root %s, want %s", c.Root, root)
+	}
+	if _, ok := c.Assets["US/Text/Story_TxtRes.uasset"]; !ok {
+		t.Errorf("AddInput: asset paths are not updated (%v)", c.Assets)
+	}
+	if path := c.RelPath(filepath.Join(root, "JP", "Text", "Story_TxtRes.uasset")); path != "JP/Text/Story_TxtRes.uasset" {
+		t.Errorf("RelPath: got %s", path)
+	}
+
+	// Assets out of the input path are not removed
+	c.Assets["JP/Text/Story_TxtRes.uasset"] = &CorpusAsset{Lang: "JP"}
+	c.Assets["JP/Text/Old_TxtRes.uasset"] = &CorpusAsset{Lang: "JP"}
+	c.seen["JP/Text/Story_TxtRes.uasset"] = true
+	if removed := c.RemoveMissingAssets(filepath.Join(root, "JP")); removed != 1 {
+		t.Errorf("RemoveMissingAssets: removed %d assets, want 1", removed)
+	}
+	paths := []string{}
+	for path := range c.Assets {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	want := []string{"JP/Text/Story_TxtRes.uasset", "US/Text/Story_TxtRes.uasset"}
+	if !slices.Equal(paths, want) {
+		t.Errorf("RemoveMissingAssets: got %v, want %v", paths, want)
+	}
+}
@@ -1,7 +1,10 @@
 package core
 
 import (
+	"encoding/json"
 	"fmt"
+	"os"
+	"regexp"
 	"slices"
 	"strings"
 	"unicode/utf8"
@@ -21,13 +24,22 @@ func GetCharWidth(uexp *Uexp) int {
 	return 1
 }
 
-// concat some short lines
-func (e *Entry) ConcatLines(charWidth int) {
+// concat some short lines.
+// Widths are counted by characters when measure is nil.
+func (e *Entry) ConcatLines(charWidth int, maxWidth int, measure WidthFunc) {
 	sep := " "
 	if charWidth == 2 {
 		// Asian languages use full width characters
 		sep = "　"
 	}
+	sepWidth := charWidth
+	if measure == nil {
+		measure = func(line string) int {
+			return utf8.RuneCountInString(line) * charWidth
+		}
+	} else {
+		sepWidth = measure(sep)
+	}
 
 	lines := strings.Split(e.Text, "\r\n")
 	newLines := []string{}
@@ -35,12 +47,12 @@ func (e *Entry) ConcatLines(charWidth int) {
 	width := 0
 	for i := range len(lines) {
 		s := lines[i]
-		newWidth := width + utf8.RuneCountInString(s)*charWidth
+		newWidth := width + measure(s)
 		if len(newStr) > 0 {
-			newWidth += charWidth
+			newWidth += sepWidth
 		}
 
-		if newWidth < 68 {
+		if newWidth < maxWidth {
 			if len(newStr) == 0 {
 				newStr = s
 			} else {
@@ -61,11 +73,227 @@ func (e *Entry) ConcatLines(charWidth int) {
 	e.Text = strings.Join(newLines, "\r\n")
 }
 
-func MakeDualsub(uexp1 *Uexp, uexp2 *Uexp) int {
+var DUALSUB_OVERFLOW_LIST = []string{
+	"error",    // throws an error
+	"skip",     // keeps the first language only
+	"truncate", // drops the last lines of the second language
+}
+
+var DUALSUB_WRAP_LIST = []string{
+	"concat", // joins short lines only
+	"rewrap", // joins all lines and wraps them with WrapText
+}
+
+var DUALSUB_ORDER_LIST = []string{
+	"first", // the first language on top
+	"second",
+}
+
+// Rules to compose dual subtitles
+type DualsubProfile struct {
+	Separator  string         `json:"separator"`
+	WrapWidth  map[string]int `json:"wrap_width"` // language -> width. "default" is used for other languages. pixels when Font is set
+	Wrap       string         `json:"wrap"`
+	MaxLines   int            `json:"max_lines"`
+	Overflow   string         `json:"overflow"`
+	Order      string         `json:"order"`
+	Categories []string       `json:"subtitle_categories"`
+	Include    []string       `json:"include"`      // regex for ids that should be merged even if they are not subtitles
+	Exclude    []string       `json:"exclude"`      // regex for ids that should not be merged
+	Romanize   []string       `json:"romanize"`     // languages that get a romanized line
+	Readings   string         `json:"reading_dict"` // csv file with readings for kanji
+	Font       string         `json:"font"`         // comma separated font files to measure lines in pixels
+	FontSize   float64        `json:"font_size"`    // point size of the subtitle font
+
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	dict    *ReadingDict
+	measure WidthFunc
+}
+
+func NewDualsubProfile() *DualsubProfile {
+	return &DualsubProfile{
+		Separator:  "\r\n",
+		WrapWidth:  map[string]int{},
+		Wrap:       "concat",
+		MaxLines:   6,
+		Overflow:   "error",
+		Order:      "first",
+		Categories: slices.Clone(SUBTTILE_CATEGORIES),
+		Include:    []string{},
+		Exclude:    []string{},
+		Romanize:   []string{},
+	}
+}
+
+func compilePatterns(patterns []string) []*regexp.Regexp {
+	regs := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			Throw(err)
+		}
+		regs = append(regs, re)
+	}
+	return regs
+}
+
+// Check values and compile patterns.
+// widgetWidth is used as the default wrap width when lines are measured in pixels.
+func (p *DualsubProfile) Init(widgetWidth int) {
+	if !slices.Contains(DUALSUB_OVERFLOW_LIST, p.Overflow) {
+		Throw(fmt.Errorf("unknown overflow strategy detected. (%s)", p.Overflow))
+	}
+	if !slices.Contains(DUALSUB_WRAP_LIST, p.Wrap) {
+		Throw(fmt.Errorf("unknown wrap mode detected. (%s)", p.Wrap))
+	}
+	if !slices.Contains(DUALSUB_ORDER_LIST, p.Order) {
+		Throw(fmt.Errorf("unknown order detected. (%s)", p.Order))
+	}
+	if p.MaxLines <= 0 {
+		Throw(fmt.Errorf("invalid max lines. (%d)", p.MaxLines))
+	}
+	if p.Font != "" {
+		if p.FontSize <= 0 {
+			Throw(fmt.Errorf("invalid font size. (%g)", p.FontSize))
+		}
+		fonts := []string{}
+		for _, font := range SplitList(p.Font) {
+			fonts = append(fonts, GetFullPath(font))
+		}
+		font := LoadFonts(fonts)
+		if !font.HasMetrics() {
+			Throw(fmt.Errorf("font has no metrics. (%s)", p.Font))
+		}
+		p.measure = font.WidthFunc(p.FontSize)
+	}
+	if _, ok := p.WrapWidth["default"]; !ok {
+		if p.measure != nil {
+			p.WrapWidth["default"] = widgetWidth // pixels
+		} else {
+			p.WrapWidth["default"] = 68
+		}
+	}
+	for _, lang := range p.Romanize {
+		if !slices.Contains(ROMANIZE_LANGS, lang) {
+			Throw(fmt.Errorf("unsupported language for romanization detected. (%s)", lang))
+		}
+	}
+	p.include = compilePatterns(p.Include)
+	p.exclude = compilePatterns(p.Exclude)
+	if p.Readings != "" {
+		p.dict = LoadReadingDict(p.Readings)
+	}
+}
+
+// Load a profile from json. Missing values use the default values.
+func LoadDualsubProfile(filePath string, widgetWidth int) *DualsubProfile {
+	profile := NewDualsubProfile()
+	if filePath != "" {
+		Printf("Reading %s...\n", filePath)
+		jsonData, err := os.ReadFile(filePath)
+		if err != nil {
+			Throw(err)
+		}
+		if err := json.Unmarshal(jsonData, profile); err != nil {
+			Throw(err)
+		}
+	}
+	profile.Init(widgetWidth)
+	return profile
+}
+
+func (p *DualsubProfile) GetWrapWidth(lang string) int {
+	if width, ok := p.WrapWidth[lang]; ok {
+		return width
+	}
+	return p.WrapWidth["default"]
+}
+
+// Get a romanized line for text. It returns "" when the language is not a target.
+// The line is wrapped with the same width as the text, so it can have line breaks.
+func (p *DualsubProfile) GetRomanizedLine(text string, lang string) string {
+	if text == "" || !slices.Contains(p.Romanize, lang) {
+		return ""
+	}
+	roma := Entry{Text: Romanize(text, lang, p.dict)}
+	roma.Wrap(p.GetWrapWidth(lang), p.measure)
+	return roma.Text
+}
+
+func appendLine(text string, line string) string {
+	if line == "" {
+		return text
+	}
+	return text + "\r\n" + line
+}
+
+func (p *DualsubProfile) IsTarget(e *Entry) bool {
+	for _, re := range p.exclude {
+		if re.MatchString(e.Id) {
+			return false
+		}
+	}
+	if e.IsSubtitleWithCategories(p.Categories) {
+		return true
+	}
+	for _, re := range p.include {
+		if re.MatchString(e.Id) {
+			return true
+		}
+	}
+	return false
+}
+
+// Count lines of text. Empty text has no lines.
+func countTextLines(text string) int {
+	if text == "" {
+		return 0
+	}
+	return strings.Count(text, "\n") + 1
+}
+
+// Count lines that a separator adds between two texts.
+// It can add extra lines (e.g. "\r\n--\r\n"), or put two texts on a line (e.g. " / ").
+func countSeparatorLines(sep string) int {
+	return strings.Count(sep, "\n") - 1
+}
+
+// Split point of a merged entry
+type DualsubSplit struct {
+	Id         string         `json:"id"`
+	TopLines   int            `json:"top_lines"`             // number of lines of the top language. 0 means the text has the second language only
+	Romanized  map[string]int `json:"romanized,omitempty"`   // language -> number of romanized lines at the end
+	FirstText  string         `json:"first_text,omitempty"`  // text of the first language before lines are joined
+	SecondText string         `json:"second_text,omitempty"` // text of the second language before lines are joined or truncated
+	Truncated  bool           `json:"truncated,omitempty"`   // the last lines of the second language are dropped
+}
+
+// Sidecar file to unmerge dual subtitles
+type DualsubSidecar struct {
+	Lang1     string         `json:"first_language"`
+	Lang2     string         `json:"second_language"`
+	Separator string         `json:"separator"`
+	Order     string         `json:"order"`
+	Entries   []DualsubSplit `json:"entries"`
+}
+
+func GetSidecarPath(uassetPath string) string {
+	return RemoveExtension(uassetPath) + ".dualsub.json"
+}
+
+func MakeDualsub(uexp1 *Uexp, uexp2 *Uexp, profile *DualsubProfile) (int, *DualsubSidecar) {
+	sidecar := &DualsubSidecar{
+		Lang1:     uexp1.Lang,
+		Lang2:     uexp2.Lang,
+		Separator: profile.Separator,
+		Order:     profile.Order,
+		Entries:   []DualsubSplit{},
+	}
 	count := 0
 	for i2 := range len(uexp2.Entries) {
 		e2 := &uexp2.Entries[i2]
-		if !e2.IsSubtitle() {
+		if !profile.IsTarget(e2) {
 			continue
 		}
 
@@ -82,16 +310,288 @@ func MakeDualsub(uexp1 *Uexp, uexp2 *Uexp) int {
 			continue
 		}
 
-		if e1.CountLines() > 1 || e2.CountLines() > 1 {
-			e1.ConcatLines(GetCharWidth(uexp1))
-			e2.ConcatLines(GetCharWidth(uexp2))
-			if e1.CountLines()+e2.CountLines() > 6 {
-				Throw(fmt.Errorf("unexpected line count detected: %s, %d, %d", e1.Id, e1.CountLines(), e2.CountLines()))
+		if e2.Text == "" {
+			continue // Nothing to merge
+		}
+
+		// Edit copies so that skipped entries keep their texts
+		t1 := Entry{Id: e1.Id, Text: e1.Text}
+		t2 := Entry{Id: e2.Id, Text: e2.Text}
+		if profile.Wrap == "rewrap" {
+			t1.Rewrap(GetCharWidth(uexp1), profile.GetWrapWidth(uexp1.Lang), profile.measure)
+			t2.Rewrap(GetCharWidth(uexp2), profile.GetWrapWidth(uexp2.Lang), profile.measure)
+		} else if t1.CountLines() > 1 || t2.CountLines() > 1 {
+			t1.ConcatLines(GetCharWidth(uexp1), profile.GetWrapWidth(uexp1.Lang), profile.measure)
+			t2.ConcatLines(GetCharWidth(uexp2), profile.GetWrapWidth(uexp2.Lang), profile.measure)
+		}
+
+		// Romanized lines are added under the texts
+		roma1 := profile.GetRomanizedLine(t1.Text, uexp1.Lang)
+		roma2 := profile.GetRomanizedLine(t2.Text, uexp2.Lang)
+		romaLines1, romaLines2 := countTextLines(roma1), countTextLines(roma2)
+
+		lines1, lines2 := countTextLines(t1.Text)+romaLines1, countTextLines(t2.Text)+romaLines2
+		sepLines := 0
+		if lines1 > 0 && lines2 > 0 {
+			sepLines = countSeparatorLines(profile.Separator)
+		}
+		truncated := false
+		if lines1+lines2+sepLines > profile.MaxLines {
+			msg := fmt.Sprintf("unexpected line count detected: %s, %d, %d", e1.Id, lines1, lines2)
+			if profile.Overflow == "error" {
+				Throw(msg)
+			} else if profile.Overflow == "skip" || lines1+sepLines+romaLines2 >= profile.MaxLines {
+				Warn("%s (skipped)", msg)
+				continue
 			}
+			// Drop the last lines of the second language.
+			// The romanized line is made again from the rest, and it can be wrapped differently.
+			lines := strings.Split(t2.Text, "\r\n")
+			n := profile.MaxLines - lines1 - sepLines - romaLines2
+			for ; n > 0; n-- {
+				t2.Text = strings.Join(lines[:n], "\r\n")
+				roma2 = profile.GetRomanizedLine(t2.Text, uexp2.Lang)
+				if lines1+sepLines+n+countTextLines(roma2) <= profile.MaxLines {
+					break
+				}
+			}
+			if n == 0 {
+				Warn("%s (skipped)", msg)
+				continue
+			}
+			Warn("%s (truncated)", msg)
+			truncated = true
+		}
+		firstText, secondText := "", ""
+		if t1.Text != e1.Text {
+			firstText = e1.Text
+		}
+		if t2.Text != e2.Text {
+			secondText = e2.Text
+		}
+		e1.Text, e2.Text = t1.Text, t2.Text
+
+		romanized := map[string]int{}
+		if roma1 != "" {
+			e1.Text = appendLine(e1.Text, roma1)
+			romanized[uexp1.Lang] = countTextLines(roma1)
+		}
+		if roma2 != "" {
+			e2.Text = appendLine(e2.Text, roma2)
+			romanized[uexp2.Lang] = countTextLines(roma2)
+		}
+
+		// Record the split point
+		split := DualsubSplit{Id: e1.Id, FirstText: firstText, SecondText: secondText, Truncated: truncated}
+		if len(romanized) > 0 {
+			split.Romanized = romanized
+		}
+		if e1.Text != "" {
+			split.TopLines = e1.CountLines()
+			if profile.Order != "first" {
+				split.TopLines = e2.CountLines()
+			}
+		}
+		sidecar.Entries = append(sidecar.Entries, split)
+
+		// Concat subtitles with the separator
+		if profile.Order == "first" {
+			e1.Merge(e2, profile.Separator)
+		} else {
+			merged := Entry{Text: e2.Text}
+			merged.Merge(e1, profile.Separator)
+			e1.Text = merged.Text
 		}
+		count++
+	}
+	return count, sidecar
+}
 
-		// Concat subtitles with line feed
-		e1.Merge(e2, "\r\n")
+// Split a merged text into the top text and the bottom text
+func splitMergedText(text string, sep string, topLines int) (string, string, bool) {
+	offset := 0
+	for {
+		i := strings.Index(text[offset:], sep)
+		if i < 0 {
+			return "", "", false
+		}
+		p := offset + i
+		lines := strings.Count(text[:p], "\n") + 1
+		if lines == topLines {
+			return text[:p], text[p+len(sep):], true
+		} else if lines > topLines {
+			return "", "", false
+		}
+		offset = p + 1
+	}
+}
+
+func removeLastLines(text string, count int) string {
+	for range count {
+		i := strings.LastIndex(text, "\r\n")
+		if i < 0 {
+			return ""
+		}
+		text = text[:i]
+	}
+	return text
+}
+
+// Remove line breaks and spaces that ConcatLines and Rewrap can add or remove
+func removeLineBreaks(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '\r' || r == '\n' || r == ' ' || r == '　' {
+			return -1
+		}
+		return r
+	}, text)
+}
+
+// Get the text before merging unless it was edited after merging.
+// A truncated text only has the first part of the original text.
+func restoreSplitText(text string, orig string, truncated bool) (string, bool) {
+	if orig == "" {
+		return text, true
+	}
+	stripped, origStripped := removeLineBreaks(text), removeLineBreaks(orig)
+	if stripped == origStripped || (truncated && strings.HasPrefix(origStripped, stripped)) {
+		return orig, true
+	}
+	return text, false
+}
+
+// Rebuild texts of both languages from merged entries.
+// uexp1 will have the first language. The returned uexp has the second language.
+func Unmerge(uexp1 *Uexp, sidecar *DualsubSidecar) *Uexp {
+	uexp2 := &Uexp{Lang: sidecar.Lang2, Entries: []Entry{}}
+	for i, split := range sidecar.Entries {
+		j := uexp1.FindEntry(split.Id, min(i, len(uexp1.Entries)-1))
+		if j < 0 {
+			Throw(fmt.Errorf("entry not found in merged asset. (%s)", split.Id))
+		}
+		e1 := &uexp1.Entries[j]
+		text1, text2 := "", e1.Text
+		if split.TopLines > 0 {
+			top, bottom, ok := splitMergedText(e1.Text, sidecar.Separator, split.TopLines)
+			if !ok {
+				Throw(fmt.Errorf("failed to find split point. (%s)", split.Id))
+			}
+			text1, text2 = top, bottom
+			if sidecar.Order != "first" {
+				text1, text2 = bottom, top
+			}
+		}
+		text1 = removeLastLines(text1, split.Romanized[sidecar.Lang1])
+		text2 = removeLastLines(text2, split.Romanized[sidecar.Lang2])
+		// Restore lines joined or dropped by dualsub mode
+		text1, ok := restoreSplitText(text1, split.FirstText, false)
+		if !ok {
+			Warn("first language was edited after merging. joined lines are not restored. (%s)", split.Id)
+		}
+		text2, ok = restoreSplitText(text2, split.SecondText, split.Truncated)
+		if !ok {
+			Warn("second language was edited after merging. joined or dropped lines are not restored. (%s)", split.Id)
+		}
+		e1.Text = text1
+		uexp2.Entries = append(uexp2.Entries, Entry{Id: e1.Id, Text: text2})
+	}
+	return uexp2
+}
+
+// Stack subtitles of N languages into uexps[0].
+// order has indices of uexps from top to bottom.
+// All languages share the line budget of the profile.
+func MakeMultisub(uexps []*Uexp, order []int, profile *DualsubProfile) int {
+	base := uexps[0]
+	count := 0
+	sepLines := countSeparatorLines(profile.Separator)
+	for i := range len(base.Entries) {
+		id := base.Entries[i].Id
+		entries := []*Entry{}
+		langs := []string{}
+		charWidths := []int{}
+		wrapWidths := []int{}
+		valid := true
+		for _, j := range order {
+			uexp := uexps[j]
+			k := uexp.FindEntry(id, min(i, len(uexp.Entries)-1))
+			if k < 0 {
+				continue
+			}
+			e := &uexp.Entries[k]
+			if e.Id == e.Text {
+				// Some entries have their own id as text. Do not edit them.
+				valid = false
+				break
+			}
+			if e.Text == "" {
+				continue
+			}
+			entries = append(entries, e)
+			langs = append(langs, uexp.Lang)
+			charWidths = append(charWidths, GetCharWidth(uexp))
+			wrapWidths = append(wrapWidths, profile.GetWrapWidth(uexp.Lang))
+		}
+		if !valid || len(entries) < 2 || !profile.IsTarget(&base.Entries[i]) {
+			continue
+		}
+
+		// Copy texts since an entry of uexps[0] is also used as output
+		texts := make([]Entry, len(entries))
+		romaLines := make([]int, len(entries))
+		multiLine := false
+		for j, e := range entries {
+			texts[j] = Entry{Id: e.Id, Text: e.Text}
+			multiLine = multiLine || e.CountLines() > 1
+		}
+		totalLines := sepLines * (len(texts) - 1)
+		for j := range len(texts) {
+			if profile.Wrap == "rewrap" {
+				texts[j].Rewrap(charWidths[j], wrapWidths[j], profile.measure)
+			} else if multiLine {
+				texts[j].ConcatLines(charWidths[j], wrapWidths[j], profile.measure)
+			}
+			roma := profile.GetRomanizedLine(texts[j].Text, langs[j])
+			texts[j].Text = appendLine(texts[j].Text, roma)
+			romaLines[j] = countTextLines(roma)
+			totalLines += texts[j].CountLines()
+		}
+
+		if totalLines > profile.MaxLines {
+			msg := fmt.Sprintf("unexpected line count detected: %s, %d", id, totalLines)
+			if profile.Overflow == "error" {
+				Throw(msg)
+			} else if profile.Overflow == "skip" {
+				Warn("%s (skipped)", msg)
+				continue
+			}
+			// Drop lines from the bottom language
+			Warn("%s (truncated)", msg)
+			for totalLines > profile.MaxLines && len(texts) > 1 {
+				j := len(texts) - 1
+				lines := strings.Split(texts[j].Text, "\r\n")
+				if len(lines) == 1 {
+					texts = texts[:j]
+					totalLines -= 1 + sepLines
+				} else {
+					if romaLines[j] > 0 {
+						Warn("romanized line of %s is dropped: %s", langs[j], id)
+						romaLines[j] = 0
+					}
+					texts[j].Text = strings.Join(lines[:len(lines)-1], "\r\n")
+					totalLines--
+				}
+			}
+			if len(texts) < 2 {
+				continue
+			}
+		}
+
+		merged := texts[0]
+		for j := 1; j < len(texts); j++ {
+			merged.Merge(&texts[j], profile.Separator)
+		}
+		base.Entries[i].Text = merged.Text
 		count++
 	}
 	return count
@@ -0,0 +1,156 @@
+package core
+
+import "testing"
+
+func newTestUexps(text1 string, text2 string) (*Uexp, *Uexp) {
+	uexp1 := &Uexp{Lang: "US", Entries: []Entry{{Id: "$story_MAIN_01", Text: text1}}}
+	uexp2 := &Uexp{Lang: "FR", Entries: []Entry{{Id: "$story_MAIN_01", Text: text2}}}
+	return uexp1, uexp2
+}
+
+func newTestProfile(sep string, maxLines int, overflow string, wrapWidth int) *DualsubProfile {
+	profile := NewDualsubProfile()
+	profile.WrapWidth["default"] = wrapWidth
+	profile.Separator = sep
+	profile.MaxLines = maxLines
+	profile.Overflow = overflow
+	profile.Init(SUBTITLE_WIDGET_WIDTH)
+	return profile
+}
+
+func TestMakeDualsub(t *testing.T) {
+	tests := []struct {
+		text1    string
+		text2    string
+		sep      string
+		maxLines int
+		overflow string
+		merged   string
+	}{
+		{"a", "b", "\r\n", 2, "error", "a\r\nb"},
+		// Separators without line breaks put texts on a line
+		{"a\r\nb", "c\r\nd", " / ", 3, "error", "a\r\nb / c\r\nd"},
+		// Separators can add lines
+		{"a", "b", "\r\n--\r\n", 3, "error", "a\r\n--\r\nb"},
+		{"a", "b", "\r\n--\r\n", 2, "skip", "a"},
+		// Empty texts are not merged with separators
+		{"", "b", "\r\n--\r\n", 1, "error", "b"},
+		{"a\r\nb\r\nc", "d\r\ne", "\r\n", 4, "truncate", "a\r\nb\r\nc\r\nd"},
+	}
+	for _, test := range tests {
+		uexp1, uexp2 := newTestUexps(test.text1, test.text2)
+		MakeDualsub(uexp1, uexp2, newTestProfile(test.sep, test.maxLines, test.overflow, 1))
+		if merged := uexp1.Entries[0].Text; merged != test.merged {
+			t.Errorf("MakeDualsub(%q, %q, %q): got %q, want %q", test.text1, test.text2, test.sep, merged, test.merged)
+		}
+	}
+}
+
+func TestDualsubSkipKeepsText(t *testing.T) {
+	// Lines of the first language should not be joined when the entry is skipped
+	uexp1, uexp2 := newTestUexps("a\r\nb\r\nc", "d\r\ne\r\nf")
+	count, _ := MakeDualsub(uexp1, uexp2, newTestProfile("\r\n", 1, "skip", 68))
+	if count != 0 || uexp1.Entries[0].Text != "a\r\nb\r\nc" {
+		t.Errorf("MakeDualsub: got %d, %q", count, uexp1.Entries[0].Text)
+	}
+}
+
+func TestDualsubOverflowError(t *testing.T) {
+	uexp1, uexp2 := newTestUexps("a\r\nb", "c\r\nd")
+	err := Try(func() { MakeDualsub(uexp1, uexp2, newTestProfile("\r\n", 3, NewDualsubProfile().Overflow, 1)) })
+	if err == nil {
+		t.Errorf("MakeDualsub: overflow should be an error by default")
+	}
+}
+
+func TestUnmerge(t *testing.T) {
+	uexp1, uexp2 := newTestUexps("a\r\nb", "c")
+	_, sidecar := MakeDualsub(uexp1, uexp2, newTestProfile("\r\n", 6, "error", 68))
+	if uexp1.Entries[0].Text != "a b\r\nc" {
+		t.Fatalf("MakeDualsub: got %q", uexp1.Entries[0].Text)
+	}
+	merged := uexp1.Entries[0].Text
+
+	// Joined lines are split again
+	unmerged2 := Unmerge(uexp1, sidecar)
+	if uexp1.Entries[0].Text != "a\r\nb" || unmerged2.Entries[0].Text != "c" {
+		t.Errorf("Unmerge: got %q, %q", uexp1.Entries[0].Text, unmerged2.Entries[0].Text)
+	}
+
+	// Edited texts are kept
+	uexp1.Entries[0].Text = "A b\r\n" + merged[len("a b\r\n"):]
+	Unmerge(uexp1, sidecar)
+	if uexp1.Entries[0].Text != "A b" {
+		t.Errorf("Unmerge: got %q", uexp1.Entries[0].Text)
+	}
+}
+
+func TestUnmergeRewrapTruncate(t *testing.T) {
+	uexp1, uexp2 := newTestUexps("Hi\r\nCloud", "Allons\r\nà la gare maintenant.")
+	profile := newTestProfile("\r\n", 3, "truncate", 10)
+	profile.Wrap = "rewrap"
+	_, sidecar := MakeDualsub(uexp1, uexp2, profile)
+	merged := uexp1.Entries[0].Text
+	if merged != "Hi Cloud\r\nAllons à\r\nla gare" {
+		t.Fatalf("MakeDualsub: got %q", merged)
+	}
+
+	// Both languages get their original texts back
+	unmerged2 := Unmerge(uexp1, sidecar)
+	if uexp1.Entries[0].Text != "Hi\r\nCloud" || unmerged2.Entries[0].Text != "Allons\r\nà la gare maintenant." {
+		t.Errorf("Unmerge: got %q, %q", uexp1.Entries[0].Text, unmerged2.Entries[0].Text)
+	}
+
+	// Edited texts are kept
+	uexp1.Entries[0].Text = "Hi Cloud\r\nAllons à\r\nla plage"
+	unmerged2 = Unmerge(uexp1, sidecar)
+	if uexp1.Entries[0].Text != "Hi\r\nCloud" || unmerged2.Entries[0].Text != "Allons à\r\nla plage" {
+		t.Errorf("Unmerge: got %q, %q", uexp1.Entries[0].Text, unmerged2.Entries[0].Text)
+	}
+}
+
+func TestRomanizedLines(t *testing.T) {
+	profile := NewDualsubProfile()
+	profile.Romanize = []string{"JP"}
+	profile.WrapWidth["JP"] = 8
+	profile.Init(SUBTITLE_WIDGET_WIDTH)
+	roma := profile.GetRomanizedLine("おはよう、クラウド", "JP")
+	if roma != "ohayou,\r\nkuraudo" {
+		t.Fatalf("GetRomanizedLine: got %q", roma)
+	}
+
+	// Wrapped lines count toward max_lines and are removed by unmerge mode
+	profile.MaxLines = 3
+	uexp1 := &Uexp{Lang: "JP", Entries: []Entry{{Id: "$story_MAIN_01", Text: "おはよう、クラウド"}}}
+	uexp2 := &Uexp{Lang: "US", Entries: []Entry{{Id: "$story_MAIN_01", Text: "Morning"}}}
+	if err := Try(func() { MakeDualsub(uexp1, uexp2, profile) }); err == nil {
+		t.Errorf("MakeDualsub: romanized lines should count toward max_lines")
+	}
+	profile.MaxLines = 6
+	_, sidecar := MakeDualsub(uexp1, uexp2, profile)
+	uexp2 = Unmerge(uexp1, sidecar)
+	if uexp1.Entries[0].Text != "おはよう、クラウド" || uexp2.Entries[0].Text != "Morning" {
+		t.Errorf("Unmerge: got %q, %q", uexp1.Entries[0].Text, uexp2.Entries[0].Text)
+	}
+}
+
+func TestTruncateRomanizedLines(t *testing.T) {
+	profile := NewDualsubProfile()
+	profile.Romanize = []string{"JP"}
+	profile.WrapWidth["JP"] = 8
+	profile.MaxLines = 4
+	profile.Overflow = "truncate"
+	profile.Init(SUBTITLE_WIDGET_WIDTH)
+
+	// The romanized line is made again from the truncated text
+	uexp1 := &Uexp{Lang: "US", Entries: []Entry{{Id: "$story_MAIN_01", Text: "Morning"}}}
+	uexp2 := &Uexp{Lang: "JP", Entries: []Entry{{Id: "$story_MAIN_01", Text: "おはよう\r\nクラウド"}}}
+	_, sidecar := MakeDualsub(uexp1, uexp2, profile)
+	if merged := uexp1.Entries[0].Text; merged != "Morning\r\nおはよう\r\nohayou" {
+		t.Fatalf("MakeDualsub: got %q", merged)
+	}
+	uexp2 = Unmerge(uexp1, sidecar)
+	if uexp1.Entries[0].Text != "Morning" || uexp2.Entries[0].Text != "おはよう\r\nクラウド" {
+		t.Errorf("Unmerge: got %q, %q", uexp1.Entries[0].Text, uexp2.Entries[0].Text)
+	}
+}
@@ -0,0 +1,451 @@
+package core
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//go:embed web
+var webFiles embed.FS
+
+// Max number of entries in a page
+const EDITOR_MAX_PAGE_SIZE = 500
+
+// An asset opened in the editor
+type editorAsset struct {
+	path     string // relative path from the root
+	uasset   *Uasset
+	orig     []Entry          // texts when the asset is loaded or saved
+	refs     map[string]*Uexp // reference language -> texts
+	modified bool
+}
+
+// Text editor for assets. It serves a REST API and a web UI.
+type Editor struct {
+	mutex    sync.Mutex
+	root     string
+	refRoots []string // assets of reference languages
+	outdir   string
+	assets   []*editorAsset
+}
+
+func copyEntries(entries []Entry) []Entry {
+	copied := make([]Entry, len(entries))
+	for i, e := range entries {
+		copied[i] = e
+		copied[i].SubEntries = slices.Clone(e.SubEntries)
+	}
+	return copied
+}
+
+// Load assets from a file or a directory.
+// Reference assets should have the same relative paths as the edited assets.
+func NewEditor(root string, refRoots []string, outdir string) *Editor {
+	ed := &Editor{root: root, refRoots: refRoots, outdir: outdir, assets: []*editorAsset{}}
+	paths := []string{}
+	baseDir := root
+	if PathIsDir(root) {
+		err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && filepath.Ext(path) == ".uasset" {
+				paths = append(paths, path)
+			}
+			return nil
+		})
+		if err != nil {
+			Throw(err)
+		}
+	} else {
+		paths = append(paths, root)
+		baseDir = filepath.Dir(root)
+	}
+	for _, path := range paths {
+		relPath, err := filepath.Rel(baseDir, path)
+		if err != nil {
+			Throw(err)
+		}
+		uasset := &Uasset{}
+		uasset.ReadFromFile(path)
+		ed.assets = append(ed.assets, &editorAsset{
+			path:   filepath.ToSlash(relPath),
+			uasset: uasset,
+			orig:   copyEntries(uasset.Uexp.Entries),
+		})
+	}
+	return ed
+}
+
+func (ed *Editor) findAsset(path string) *editorAsset {
+	for _, asset := range ed.assets {
+		if asset.path == path {
+			return asset
+		}
+	}
+	return nil
+}
+
+// Load assets of reference languages when they are needed
+func (ed *Editor) loadRefs(asset *editorAsset) {
+	if asset.refs != nil {
+		return
+	}
+	asset.refs = map[string]*Uexp{}
+	for _, refRoot := range ed.refRoots {
+		path := refRoot
+		if PathIsDir(refRoot) {
+			path = filepath.Join(refRoot, filepath.FromSlash(asset.path))
+		}
+		if !PathExists(path) {
+			continue
+		}
+		err := Try(func() {
+			uasset := &Uasset{}
+			uasset.ReadFromFile(path)
+			if uasset.Uexp.Lang != asset.uasset.Uexp.Lang {
+				asset.refs[uasset.Uexp.Lang] = uasset.Uexp
+			}
+		})
+		if err != nil {
+			Warn("failed to load reference asset. %v (%s)", err, path)
+		}
+	}
+}
+
+// Asset info for the web UI
+type EditorAssetInfo struct {
+	Path     string `json:"path"`
+	Lang     string `json:"language"`
+	Count    int    `json:"entries"`
+	Modified bool   `json:"modified"`
+}
+
+// Entry with texts of reference languages
+type EditorEntry struct {
+	Index      int               `json:"index"`
+	Id         string            `json:"id"`
+	Text       string            `json:"text"`
+	SubEntries []SubEntry        `json:"sub_entries,omitempty"`
+	References map[string]string `json:"references,omitempty"`
+	Modified   bool              `json:"modified"`
+}
+
+// Body of PUT /api/entries. Omitted texts are not changed.
+type EditorUpdate struct {
+	Id         string                 `json:"id"`
+	Text       *string                `json:"text"`
+	SubEntries []EditorSubEntryUpdate `json:"sub_entries"`
+}
+
+type EditorSubEntryUpdate struct {
+	Id   string  `json:"id"`
+	Text *string `json:"text"`
+}
+
+type EditorPage struct {
+	Total   int           `json:"total"`
+	Offset  int           `json:"offset"`
+	Entries []EditorEntry `json:"entries"`
+}
+
+func (ed *Editor) GetAssets() []EditorAssetInfo {
+	infos := make([]EditorAssetInfo, 0, len(ed.assets))
+	for _, asset := range ed.assets {
+		infos = append(infos, EditorAssetInfo{
+			Path:     asset.path,
+			Lang:     asset.uasset.Uexp.Lang,
+			Count:    len(asset.uasset.Uexp.Entries),
+			Modified: asset.modified,
+		})
+	}
+	return infos
+}
+
+func entryIsModified(e *Entry, orig *Entry) bool {
+	if e.Text != orig.Text || len(e.SubEntries) != len(orig.SubEntries) {
+		return true
+	}
+	for i := range len(e.SubEntries) {
+		if e.SubEntries[i].Text != orig.SubEntries[i].Text {
+			return true
+		}
+	}
+	return false
+}
+
+// Get entries of an asset. Entries are filtered by id or text when filter is not empty.
+func (ed *Editor) GetPage(asset *editorAsset, offset int, limit int, filter string) EditorPage {
+	ed.loadRefs(asset)
+	uexp := asset.uasset.Uexp
+	filter = strings.ToLower(filter)
+	indices := []int{}
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		if filter == "" || strings.Contains(strings.ToLower(e.Id), filter) || strings.Contains(strings.ToLower(e.Text), filter) {
+			indices = append(indices, i)
+		}
+	}
+
+	page := EditorPage{Total: len(indices), Offset: offset, Entries: []EditorEntry{}}
+	for _, i := range indices[min(offset, len(indices)):min(offset+limit, len(indices))] {
+		e := &uexp.Entries[i]
+		entry := EditorEntry{
+			Index:      i,
+			Id:         e.Id,
+			Text:       e.Text,
+			SubEntries: e.SubEntries,
+			References: map[string]string{},
+			Modified:   entryIsModified(e, &asset.orig[i]),
+		}
+		for lang, ref := range asset.refs {
+			if j := ref.FindEntry(e.Id, min(i, len(ref.Entries)-1)); j >= 0 {
+				entry.References[lang] = ref.Entries[j].Text
+			}
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	return page
+}
+
+// Apply an update to a copy of the entry. Only texts in the update are changed.
+func applyEditorUpdate(e *Entry, update *EditorUpdate) (Entry, error) {
+	edited := *e
+	edited.SubEntries = slices.Clone(e.SubEntries)
+	if update.Text != nil {
+		edited.Text = *update.Text
+	}
+	for _, seUpdate := range update.SubEntries {
+		j := slices.IndexFunc(edited.SubEntries, func(se SubEntry) bool { return se.Id == seUpdate.Id })
+		if j < 0 {
+			return edited, fmt.Errorf("unknown sub entry id detected (%s)", seUpdate.Id)
+		}
+		if seUpdate.Text != nil {
+			edited.SubEntries[j].Text = *seUpdate.Text
+		}
+	}
+	return edited, nil
+}
+
+// Edit a text and sub entries. It returns markup issues compared to the original text.
+func (ed *Editor) Edit(asset *editorAsset, update *EditorUpdate) ([]string, error) {
+	uexp := asset.uasset.Uexp
+	i := uexp.FindEntry(update.Id, 0)
+	if i < 0 {
+		return nil, fmt.Errorf("entry not found. (%s)", update.Id)
+	}
+	e := &uexp.Entries[i]
+	edited, err := applyEditorUpdate(e, update)
+	if err != nil {
+		return nil, err
+	}
+	*e = edited
+
+	orig := &asset.orig[i]
+	warnings := CompareMarkup(orig.Text, e.Text)
+	for j, se := range e.SubEntries {
+		for _, msg := range CompareMarkup(orig.SubEntries[j].Text, se.Text) {
+			warnings = append(warnings, se.Id+": "+msg)
+		}
+	}
+	asset.modified = false
+	for j := range len(uexp.Entries) {
+		if entryIsModified(&uexp.Entries[j], &asset.orig[j]) {
+			asset.modified = true
+			break
+		}
+	}
+	return warnings, nil
+}
+
+// Save modified assets into outdir. It returns the written paths.
+func (ed *Editor) Save() ([]string, error) {
+	saved := []string{}
+	for _, asset := range ed.assets {
+		if !asset.modified {
+			continue
+		}
+		outPath := filepath.Join(ed.outdir, filepath.FromSlash(asset.path))
+		err := Try(func() {
+			MakeDir(filepath.Dir(outPath))
+			asset.uasset.WriteToFile(outPath)
+		})
+		if err != nil {
+			return saved, err
+		}
+		asset.orig = copyEntries(asset.uasset.Uexp.Entries)
+		asset.modified = false
+		saved = append(saved, outPath)
+	}
+	return saved, nil
+}
+
+func writeJson(w http.ResponseWriter, status int, body interface{}) {
+	data, err := JSONMarshal(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+func writeJsonError(w http.ResponseWriter, status int, err error) {
+	writeJson(w, status, map[string]string{"error": err.Error()})
+}
+
+// Check the Host header to block DNS rebinding.
+// Only the bound address is allowed. (localhost is also allowed for loopback addresses, and any host for unspecified addresses.)
+func hostIsAllowed(host string, addr string) bool {
+	if host == addr {
+		return true
+	}
+	name, port, err := net.SplitHostPort(host)
+	boundHost, boundPort, _ := net.SplitHostPort(addr)
+	if err != nil || port != boundPort {
+		return false
+	}
+	ip := net.ParseIP(boundHost)
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() {
+		return name == "localhost"
+	}
+	return ip.IsUnspecified()
+}
+
+// Check if the request has a JSON body. Browsers cannot send it to other origins without CORS preflight.
+func isJsonRequest(r *http.Request) bool {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return err == nil && mediaType == "application/json"
+}
+
+func getIntParam(r *http.Request, key string, defaultValue int) int {
+	value, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil || value < 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// Get a handler for the REST API and the web UI.
+// addr is the bound address (host:port) that requests should have in the Host header.
+// PUT and POST need "Content-Type: application/json".
+//
+//	GET  /api/assets
+//	GET  /api/entries?asset=path&offset=0&limit=100&filter=text
+//	GET  /api/search?q=text&regex=1&ignore_case=1&fields=text,sub
+//	PUT  /api/entries?asset=path  (body: {"id": "...", "text": "...", "sub_entries": [...]})
+//	POST /api/save
+func (ed *Editor) Handler(addr string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/api/assets", func(w http.ResponseWriter, r *http.Request) {
+		ed.mutex.Lock()
+		defer ed.mutex.Unlock()
+		writeJson(w, http.StatusOK, ed.GetAssets())
+	})
+
+	mux.HandleFunc("/api/entries", func(w http.ResponseWriter, r *http.Request) {
+		ed.mutex.Lock()
+		defer ed.mutex.Unlock()
+		asset := ed.findAsset(r.URL.Query().Get("asset"))
+		if asset == nil {
+			writeJsonError(w, http.StatusNotFound, fmt.Errorf("asset not found. (%s)", r.URL.Query().Get("asset")))
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			limit := min(getIntParam(r, "limit", 100), EDITOR_MAX_PAGE_SIZE)
+			page := ed.GetPage(asset, getIntParam(r, "offset", 0), limit, r.URL.Query().Get("filter"))
+			writeJson(w, http.StatusOK, page)
+		case http.MethodPut:
+			update := &EditorUpdate{}
+			if err := json.NewDecoder(r.Body).Decode(update); err != nil {
+				writeJsonError(w, http.StatusBadRequest, err)
+				return
+			}
+			warnings, err := ed.Edit(asset, update)
+			if err != nil {
+				writeJsonError(w, http.StatusBadRequest, err)
+				return
+			}
+			writeJson(w, http.StatusOK, map[string]interface{}{"warnings": warnings, "modified": asset.modified})
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	})
+
+	mux.HandleFunc("/api/search", func(w http.ResponseWriter, r *http.Request) {
+		params := r.URL.Query()
+		var query *SearchQuery
+		err := Try(func() {
+			query = NewSearchQuery(params.Get("q"), params.Get("regex") == "1", params.Get("ignore_case") == "1")
+			if fields := SplitList(params.Get("fields")); len(fields) > 0 {
+				query.SetFields(fields)
+			}
+		})
+		if err != nil {
+			writeJsonError(w, http.StatusBadRequest, err)
+			return
+		}
+		if params.Get("q") == "" {
+			writeJsonError(w, http.StatusBadRequest, fmt.Errorf("query is empty"))
+			return
+		}
+		ed.mutex.Lock()
+		defer ed.mutex.Unlock()
+		result := NewSearchResult()
+		for _, asset := range ed.assets {
+			result.Search(asset.uasset.Uexp, asset.path, query)
+		}
+		result.Sort()
+		writeJson(w, http.StatusOK, result.Hits)
+	})
+
+	mux.HandleFunc("/api/save", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		ed.mutex.Lock()
+		defer ed.mutex.Unlock()
+		saved, err := ed.Save()
+		if err != nil {
+			writeJsonError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]interface{}{"saved": saved})
+	})
+
+	static, err := fs.Sub(webFiles, "web")
+	if err != nil {
+		Throw(err)
+	}
+	mux.Handle("/", http.FileServer(http.FS(static)))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hostIsAllowed(r.Host, addr) {
+			writeJsonError(w, http.StatusForbidden, fmt.Errorf("unexpected host. (%s)", r.Host))
+			return
+		}
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && !isJsonRequest(r) {
+			writeJsonError(w, http.StatusUnsupportedMediaType, fmt.Errorf("content type should be application/json"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,58 @@
+package core
+
+import "testing"
+
+func TestHostIsAllowed(t *testing.T) {
+	tests := []struct {
+		host    string
+		addr    string
+		allowed bool
+	}{
+		{"127.0.0.1:8080", "127.0.0.1:8080", true},
+		{"localhost:8080", "127.0.0.1:8080", true},
+		{"localhost:8081", "127.0.0.1:8080", false},
+		{"evil.example:8080", "127.0.0.1:8080", false},
+		{"192.168.0.2:8080", "192.168.0.2:8080", true},
+		{"localhost:8080", "192.168.0.2:8080", false},
+		{"192.168.0.2:8080", "0.0.0.0:8080", true},
+		{"evil.example", "127.0.0.1:8080", false},
+	}
+	for _, test := range tests {
+		if allowed := hostIsAllowed(test.host, test.addr); allowed != test.allowed {
+			t.Errorf("hostIsAllowed(%q, %q): got %v, want %v", test.host, test.addr, allowed, test.allowed)
+		}
+	}
+}
+
+func TestApplyEditorUpdate(t *testing.T) {
+	text := func(s string) *string { return &s }
+	e := &Entry{Id: "id", Text: "text", SubEntries: []SubEntry{{Id: "ACTOR", Text: "Cloud"}}}
+	tests := []struct {
+		update  EditorUpdate
+		text    string
+		subText string
+	}{
+		{EditorUpdate{Id: "id"}, "text", "Cloud"}, // omitted fields are not changed
+		{EditorUpdate{Id: "id", Text: text("")}, "", "Cloud"},
+		{EditorUpdate{Id: "id", Text: text("new")}, "new", "Cloud"},
+		{EditorUpdate{Id: "id", SubEntries: []EditorSubEntryUpdate{{Id: "ACTOR"}}}, "text", "Cloud"},
+		{EditorUpdate{Id: "id", SubEntries: []EditorSubEntryUpdate{{Id: "ACTOR", Text: text("Tifa")}}}, "text", "Tifa"},
+	}
+	for i, test := range tests {
+		edited, err := applyEditorUpdate(e, &test.update)
+		if err != nil {
+			t.Errorf("test %d: %v", i, err)
+			continue
+		}
+		if edited.Text != test.text || edited.SubEntries[0].Text != test.subText {
+			t.Errorf("test %d: got %q and %q, want %q and %q", i, edited.Text, edited.SubEntries[0].Text, test.text, test.subText)
+		}
+	}
+	if e.Text != "text" || e.SubEntries[0].Text != "Cloud" {
+		t.Errorf("the original entry should not be changed")
+	}
+	unknown := EditorUpdate{Id: "id", SubEntries: []EditorSubEntryUpdate{{Id: "NAME", Text: text("")}}}
+	if _, err := applyEditorUpdate(e, &unknown); err == nil {
+		t.Errorf("unknown sub entry should return an error")
+	}
+}
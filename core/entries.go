@@ -47,22 +47,28 @@ func (e *SubEntry) UpdateNameId(uasset *Uasset) {
 	Throw(fmt.Errorf("SubEntry.Name (%s) is not found in uasset name map", e.Id))
 }
 
-func (e *SubEntry) WriteAsCsv(mainId string, w *csv.Writer) {
+func (e *SubEntry) WriteAsCsv(mainId string, w *csv.Writer, withFlag bool) {
 	record := []string{mainId, e.Id, GoStrToCsvStr(e.Text)}
+	if withFlag {
+		record = append(record, "")
+	}
 	if err := w.Write(record); err != nil {
 		Throw(err)
 	}
 }
 
 func (e *SubEntry) Print() {
-	fmt.Printf("      id: %s\n", e.Id)
-	fmt.Printf("        text: %s\n", e.Text)
+	Printf("      id: %s\n", e.Id)
+	Printf("        text: %s\n", e.Text)
 }
 
 type Entry struct {
 	Id         string     `json:"id"`
 	Text       string     `json:"text"`
 	SubEntries []SubEntry `json:"sub_entries,omitempty"`
+
+	// True when the text is filled by machine translation
+	MachineTranslated bool `json:"machine_translated,omitempty"`
 }
 
 func (e *Entry) Read(s *Serializer) {
@@ -147,6 +153,7 @@ func (e *Entry) UpdateWithCsv(row []string) {
 	sub_id := row[1]
 	if row[1] == "" {
 		e.Text = CsvStrToGoStr(row[2])
+		e.MachineTranslated = len(row) > 3 && row[3] == "1"
 		return
 	}
 	for i := range len(e.SubEntries) {
@@ -158,13 +165,21 @@ func (e *Entry) UpdateWithCsv(row []string) {
 	Throw(fmt.Errorf("unknown sub entry id detected (%s)", sub_id))
 }
 
-func (e *Entry) WriteAsCsv(w *csv.Writer) {
+// withFlag adds the machine_translated column
+func (e *Entry) WriteAsCsv(w *csv.Writer, withFlag bool) {
 	record := []string{e.Id, "", GoStrToCsvStr(e.Text)}
+	if withFlag {
+		flag := ""
+		if e.MachineTranslated {
+			flag = "1"
+		}
+		record = append(record, flag)
+	}
 	if err := w.Write(record); err != nil {
 		Throw(err)
 	}
 	for i := range len(e.SubEntries) {
-		e.SubEntries[i].WriteAsCsv(e.Id, w)
+		e.SubEntries[i].WriteAsCsv(e.Id, w, withFlag)
 	}
 }
 
@@ -176,7 +191,19 @@ var SUBTTILE_CATEGORIES = []string{
 	"CDV_",
 }
 
+// Get category of entry (e.g. "MAIN" or "QST_")
+func (e *Entry) GetCategory() string {
+	if len(e.Id) < 11 {
+		return ""
+	}
+	return e.Id[7:11]
+}
+
 func (e *Entry) IsSubtitle() bool {
+	return e.IsSubtitleWithCategories(SUBTTILE_CATEGORIES)
+}
+
+func (e *Entry) IsSubtitleWithCategories(categories []string) bool {
 	for i := range len(e.SubEntries) {
 		if e.SubEntries[i].Id == "ACTOR" {
 			return true
@@ -184,11 +211,8 @@ func (e *Entry) IsSubtitle() bool {
 	}
 	// Note: Some voice lines don't have the "ACTOR" property in FF7R2.
 	//       So, we have to check id.
-	if len(e.Id) < 11 {
-		return false
-	}
-	cat := e.Id[7:11]
-	return slices.Contains(SUBTTILE_CATEGORIES, cat) &&
+	cat := e.GetCategory()
+	return slices.Contains(categories, cat) &&
 		!strings.HasSuffix(cat, "_sys")
 }
 
@@ -212,12 +236,12 @@ func (e *Entry) Merge(e2 *Entry, sep string) {
 }
 
 func (e *Entry) Print() {
-	fmt.Printf("  id: %s\n", e.Id)
-	fmt.Printf("    text: %s\n", e.Text)
+	Printf("  id: %s\n", e.Id)
+	Printf("    text: %s\n", e.Text)
 	subEntryCount := len(e.SubEntries)
-	fmt.Printf("    sub entry count:%d\n", subEntryCount)
+	Printf("    sub entry count:%d\n", subEntryCount)
 	if subEntryCount > 0 {
-		fmt.Println("    sub entries:")
+		Println("    sub entries:")
 	}
 	for _, e := range e.SubEntries {
 		e.Print()
@@ -3,26 +3,37 @@ package core
 import (
 	"errors"
 	"fmt"
+	"io"
 	"log"
+	"os"
 	"reflect"
 	"runtime"
+	"runtime/debug"
+	"slices"
+	"strings"
 	"sync"
 )
 
+// Exit codes
+const (
+	EXIT_OK     = 0
+	EXIT_FATAL  = 1 // fatal errors (e.g. invalid options) that stop the tool
+	EXIT_FAILED = 2 // some input files failed (with or without --keep-going)
+)
+
+// A thrown error with python-like backtraces.
+// Each Throw panics with a new Error, so goroutines never share error states.
 type Error struct {
-	hasError   bool
 	err        error
 	backtraces string
 }
 
-func (e *Error) Init() {
-	e.hasError = false
-	e.err = nil
-	e.backtraces = ""
+func (e *Error) Error() string {
+	return e.err.Error()
 }
 
-func (e *Error) HasError() bool {
-	return e.hasError
+func (e *Error) Unwrap() error {
+	return e.err
 }
 
 func (e *Error) GetError() error {
@@ -38,12 +49,8 @@ func (e *Error) GetErrorWithTraces() string {
 		"%s\nError: %s\n", e.GetBacktraces(), e.GetError())
 }
 
-func (e *Error) Throw(any interface{}, traceStart int) {
-	if e.hasError {
-		return
-	}
-	e.hasError = true
-
+func newError(any interface{}, traceStart int) *Error {
+	e := &Error{}
 	switch v := any.(type) {
 	case string:
 		e.err = errors.New(v)
@@ -62,8 +69,8 @@ func (e *Error) Throw(any interface{}, traceStart int) {
 			break
 		}
 		funcName := runtime.FuncForPC(pt).Name()
-		if funcName == "runtime.goexit" {
-			break
+		if funcName == "runtime.goexit" || strings.HasSuffix(funcName, "/core.Try") {
+			break // frames out of the failed function are not needed
 		}
 		e.backtraces = fmt.Sprintf("\n  File \"%s\", line %d, in %v%s", file, line, funcName, e.backtraces)
 		if funcName == "main.main" {
@@ -72,50 +79,128 @@ func (e *Error) Throw(any interface{}, traceStart int) {
 		i += 1
 	}
 	e.backtraces = "Traceback (most recent call last):" + e.backtraces
-	panic(e.err)
+	return e
 }
 
-var globalError Error = Error{}
-
 func Throw(any interface{}) {
-	globalError.Init()
-	globalError.Throw(any, 2)
+	panic(newError(any, 2))
 }
 
 func ThrowBase(any interface{}, traceStart int) {
-	globalError.Init()
-	globalError.Throw(any, traceStart)
+	panic(newError(any, traceStart))
 }
 
-func HasError() bool {
-	return globalError.HasError()
+// Convert a recovered value to *Error. Other panics (e.g. runtime errors) keep the go stack.
+func toError(r interface{}) *Error {
+	if e, ok := r.(*Error); ok {
+		return e
+	}
+	return &Error{err: fmt.Errorf("%v", r), backtraces: string(debug.Stack())}
 }
 
-func GetError() error {
-	return globalError.GetError()
+// Call a function and get the thrown error instead of exiting.
+// It is used to keep running after errors (e.g. watch mode).
+func Try(f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = toError(r)
+		}
+	}()
+	f()
+	return nil
 }
 
-func GetErrorWithTraces() string {
-	return globalError.GetErrorWithTraces()
+var logMutex sync.Mutex
+
+func emitError(e *Error, path string) {
+	EmitProgress(ProgressEvent{
+		Event:   "error",
+		Path:    path,
+		Message: e.Error(),
+		Trace:   e.GetBacktraces(),
+	})
 }
 
-var logMutex sync.Mutex
+func exitWithError(r interface{}, msg string) {
+	e := toError(r)
+	// logMutex is never unlocked. Other goroutines that fail at the same time
+	// block here until os.Exit, so only the first error and its summary are printed.
+	logMutex.Lock()
+	emitError(e, "")
+	emitFatalSummary()
+	log.Print(msg + e.GetErrorWithTraces())
+	os.Exit(EXIT_FATAL)
+}
 
 func ErrorCheck() {
 	// catch panic and show backtraces
-	if globalError.HasError() {
-		logMutex.Lock()
-		log.Fatal(globalError.GetErrorWithTraces())
-		logMutex.Unlock()
+	if r := recover(); r != nil {
+		exitWithError(r, "")
 	}
 }
 
 func ErrorCheckWithMsg(msg string) {
 	// catch panic and show backtraces
-	if globalError.HasError() {
-		logMutex.Lock()
-		str := msg + globalError.GetErrorWithTraces()
-		log.Fatal(str)
-		logMutex.Unlock()
+	if r := recover(); r != nil {
+		exitWithError(r, msg)
+	}
+}
+
+// An input file that failed to be processed
+type FileFailure struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+	Trace string `json:"trace"`
+}
+
+// Errors of input files. Workers can add failures concurrently.
+type FailureReport struct {
+	mutex    sync.Mutex
+	Failures []FileFailure
+}
+
+func NewFailureReport() *FailureReport {
+	return &FailureReport{Failures: []FileFailure{}}
+}
+
+func (r *FailureReport) Add(path string, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{err: err}
+	}
+	emitError(e, path)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Failures = append(r.Failures, FileFailure{Path: path, Error: e.Error(), Trace: e.GetBacktraces()})
+}
+
+func (r *FailureReport) Count() int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return len(r.Failures)
+}
+
+// Get the exit code for the failures. It is the same with or without --keep-going.
+func (r *FailureReport) ExitCode() int {
+	if r.Count() > 0 {
+		return EXIT_FAILED
+	}
+	return EXIT_OK
+}
+
+func (r *FailureReport) WriteAsText(w io.Writer) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if len(r.Failures) == 1 {
+		fmt.Fprintf(w, "Failed to process 1 file.\n")
+	} else {
+		fmt.Fprintf(w, "Failed to process %d files.\n", len(r.Failures))
+	}
+	slices.SortFunc(r.Failures, func(a, b FileFailure) int {
+		return strings.Compare(a.Path, b.Path)
+	})
+	for _, f := range r.Failures {
+		fmt.Fprintf(w, "\nInput path: %s\n%s\nError: %s\n", f.Path, f.Trace, f.Error)
 	}
 }
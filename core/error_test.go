@@ -0,0 +1,83 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestFailureReport(t *testing.T) {
+	events := enableTestProgress(t)
+	report := NewFailureReport()
+	if report.ExitCode() != EXIT_OK {
+		t.Errorf("ExitCode without failures: got %d", report.ExitCode())
+	}
+
+	// Workers add failures concurrently
+	var wg sync.WaitGroup
+	for _, path := range []string{"b.uasset", "a.uasset"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if path == "a.uasset" {
+				report.Add(path, errors.New("not a text asset"))
+			} else {
+				report.Add(path, Try(func() { Throw("broken") }))
+			}
+		}()
+	}
+	wg.Wait()
+	if report.Count() != 2 || report.ExitCode() != EXIT_FAILED {
+		t.Fatalf("failures: got %d, exit code %d", report.Count(), report.ExitCode())
+	}
+
+	// Failures are sorted by paths. Thrown errors have traces.
+	out := &bytes.Buffer{}
+	report.WriteAsText(out)
+	text := out.String()
+	if !strings.HasPrefix(text, "Failed to process 2 files.\n\nInput path: a.uasset\n") ||
+		!strings.Contains(text, "Input path: b.uasset\nTraceback (most recent call last):") ||
+		!strings.HasSuffix(text, "Error: broken\n") {
+		t.Errorf("WriteAsText: got %q", text)
+	}
+
+	// Each failure is emitted as an error event
+	list := readTestEvents(t, events)
+	if len(list) != 2 || list[0].Event != "error" || list[0].Path == "" {
+		t.Errorf("events: got %s", events.String())
+	}
+}
+
+func TestExitWithError(t *testing.T) {
+	// Run the fatal error in a child process because it exits
+	if os.Getenv("TEST_EXIT_WITH_ERROR") == "1" {
+		EnableProgress(os.Stdout)
+		EmitFileDone("a.uasset", 3, progressStart)
+		defer ErrorCheck()
+		Throw("invalid option")
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestExitWithError$")
+	cmd.Env = append(os.Environ(), "TEST_EXIT_WITH_ERROR=1")
+	stderr := &bytes.Buffer{}
+	cmd.Stderr = stderr
+	stdout, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != EXIT_FATAL {
+		t.Fatalf("exit code: got %v, want %d", err, EXIT_FATAL)
+	}
+	if !strings.HasSuffix(stderr.String(), "Error: invalid option\n") {
+		t.Errorf("stderr: got %q", stderr.String())
+	}
+
+	// The error event is followed by the summary of files done before it
+	list := readTestEvents(t, bytes.NewBuffer(stdout))
+	if len(list) != 3 || list[1].Event != "error" || list[1].Message != "invalid option" ||
+		list[2].Event != "summary" || *list[2].Processed != 3 || *list[2].Failed != 0 {
+		t.Errorf("events: got %s", stdout)
+	}
+}
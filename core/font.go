@@ -0,0 +1,587 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"unicode"
+)
+
+// Tags of sfnt headers (TrueType and OpenType)
+var SFNT_TAGS = [][]byte{
+	{0x00, 0x01, 0x00, 0x00},
+	[]byte("OTTO"),
+	[]byte("true"),
+}
+
+var TTC_TAG = []byte("ttcf")
+
+// DPI that Slate uses to render fonts. Font sizes of widgets are in points.
+const FONT_DPI = 96
+
+// A font in a font file (or in a font collection)
+type fontFace struct {
+	glyphs     map[rune]uint16 // character -> glyph index
+	unitsPerEm int
+	advances   []uint16         // advance widths of hmtx. the last one is used for the rest of glyphs.
+	kerning    map[uint32]int16 // left << 16 | right -> adjustment
+}
+
+func (face *fontFace) hasMetrics() bool {
+	return face.unitsPerEm > 0 && len(face.advances) > 0
+}
+
+func (face *fontFace) getAdvance(glyph uint16) int {
+	if int(glyph) < len(face.advances) {
+		return int(face.advances[glyph])
+	}
+	return int(face.advances[len(face.advances)-1])
+}
+
+// Glyph coverage and metrics of fonts.
+// It reads cmap tables for coverage, and head, hhea, hmtx and kern tables for text width.
+type Font struct {
+	runes map[rune]bool
+	faces []*fontFace
+}
+
+func NewFont() *Font {
+	return &Font{runes: map[rune]bool{}, faces: []*fontFace{}}
+}
+
+func (f *Font) HasGlyph(r rune) bool {
+	return f.runes[r]
+}
+
+func (f *Font) CountGlyphs() int {
+	return len(f.runes)
+}
+
+// Read tables of a font file.
+// Font assets (.ufont or .uasset) are also supported since they have raw TTF/OTF data inside.
+func (f *Font) LoadFromFile(filePath string) {
+	Printf("Reading %s...\n", filePath)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		Throw(err)
+	}
+	if bytes.HasPrefix(data, TTC_TAG) {
+		f.readCollection(data)
+		return
+	}
+	offset := findSfnt(data)
+	if offset < 0 {
+		Throw(fmt.Errorf("font data not found. (%s)", filePath))
+	}
+	if err := f.readSfnt(data[offset:], 0); err != nil {
+		Throw(fmt.Errorf("%v (%s)", err, filePath))
+	}
+}
+
+// Load fonts and merge glyphs of them (e.g. a main font and a fallback font)
+func LoadFonts(filePaths []string) *Font {
+	f := NewFont()
+	for _, path := range filePaths {
+		f.LoadFromFile(path)
+	}
+	return f
+}
+
+// Find the offset of an sfnt header that has a cmap table
+func findSfnt(data []byte) int {
+	for _, tag := range SFNT_TAGS {
+		offset := 0
+		for {
+			i := bytes.Index(data[offset:], tag)
+			if i < 0 {
+				break
+			}
+			offset += i
+			if _, _, err := findTable(data[offset:], 0, "cmap"); err == nil {
+				return offset
+			}
+			offset++
+		}
+	}
+	return -1
+}
+
+func readUint16(data []byte, offset int) (uint16, error) {
+	if offset < 0 || offset+2 > len(data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return binary.BigEndian.Uint16(data[offset:]), nil
+}
+
+func readUint32(data []byte, offset int) (uint32, error) {
+	if offset < 0 || offset+4 > len(data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return binary.BigEndian.Uint32(data[offset:]), nil
+}
+
+// Find a table from the table directory. It returns the offset and the length of the table.
+func findTable(data []byte, sfntOffset int, tag string) (int, int, error) {
+	numTables, err := readUint16(data, sfntOffset+4)
+	if err != nil {
+		return 0, 0, err
+	}
+	if numTables == 0 || numTables > 256 {
+		return 0, 0, fmt.Errorf("unexpected table count: %d", numTables)
+	}
+	for i := range int(numTables) {
+		record := sfntOffset + 12 + i*16
+		if record+16 > len(data) {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		if string(data[record:record+4]) != tag {
+			continue
+		}
+		offset, _ := readUint32(data, record+8)
+		length, _ := readUint32(data, record+12)
+		if int(offset)+int(length) > len(data) {
+			return 0, 0, io.ErrUnexpectedEOF
+		}
+		return int(offset), int(length), nil
+	}
+	return 0, 0, fmt.Errorf("%s table not found", tag)
+}
+
+func (f *Font) readCollection(data []byte) {
+	numFonts, err := readUint32(data, 8)
+	if err != nil {
+		Throw(err)
+	}
+	for i := range int(numFonts) {
+		offset, err := readUint32(data, 12+i*4)
+		if err != nil {
+			Throw(err)
+		}
+		if err := f.readSfnt(data, int(offset)); err != nil {
+			Throw(err)
+		}
+	}
+}
+
+// Read all unicode subtables in cmap and metrics
+func (f *Font) readSfnt(data []byte, sfntOffset int) error {
+	face := &fontFace{glyphs: map[rune]uint16{}, kerning: map[uint32]int16{}}
+	if err := face.readCmap(data, sfntOffset); err != nil {
+		return err
+	}
+	// Fonts without metrics can still be used for glyph coverage
+	if err := face.readMetrics(data, sfntOffset); err == nil {
+		face.readKern(data, sfntOffset)
+	}
+	for c := range face.glyphs {
+		f.runes[c] = true
+	}
+	f.faces = append(f.faces, face)
+	return nil
+}
+
+func (face *fontFace) readCmap(data []byte, sfntOffset int) error {
+	cmap, length, err := findTable(data, sfntOffset, "cmap")
+	if err != nil {
+		return err
+	}
+	table := data[cmap : cmap+length]
+	numSubtables, err := readUint16(table, 2)
+	if err != nil {
+		return err
+	}
+	found := false
+	for i := range int(numSubtables) {
+		platform, err1 := readUint16(table, 4+i*8)
+		encoding, err2 := readUint16(table, 6+i*8)
+		offset, err3 := readUint32(table, 8+i*8)
+		if err1 != nil || err2 != nil || err3 != nil {
+			return io.ErrUnexpectedEOF
+		}
+		// Unicode platform, or Windows platform with Unicode BMP or full repertoire
+		if platform != 0 && !(platform == 3 && (encoding == 1 || encoding == 10)) {
+			continue
+		}
+		if err := face.readCmapSubtable(table, int(offset)); err != nil {
+			return err
+		}
+		found = true
+	}
+	if !found {
+		return fmt.Errorf("unicode cmap not found")
+	}
+	return nil
+}
+
+func (face *fontFace) setGlyph(c rune, glyph uint16) {
+	if _, ok := face.glyphs[c]; !ok {
+		face.glyphs[c] = glyph
+	}
+}
+
+func (face *fontFace) readCmapSubtable(table []byte, offset int) error {
+	format, err := readUint16(table, offset)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case 0:
+		// Byte encoding table
+		for i := range 256 {
+			if offset+6+i >= len(table) {
+				return io.ErrUnexpectedEOF
+			}
+			if table[offset+6+i] != 0 {
+				face.setGlyph(rune(i), uint16(table[offset+6+i]))
+			}
+		}
+	case 4:
+		// Segment mapping to delta values
+		segCountX2, err := readUint16(table, offset+6)
+		if err != nil {
+			return err
+		}
+		segCount := int(segCountX2 / 2)
+		endCodes := offset + 14
+		startCodes := endCodes + segCount*2 + 2
+		idDeltas := startCodes + segCount*2
+		idRangeOffsets := idDeltas + segCount*2
+		for i := range segCount {
+			end, err1 := readUint16(table, endCodes+i*2)
+			start, err2 := readUint16(table, startCodes+i*2)
+			delta, err3 := readUint16(table, idDeltas+i*2)
+			rangeOffset, err4 := readUint16(table, idRangeOffsets+i*2)
+			if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+				return io.ErrUnexpectedEOF
+			}
+			for c := int(start); c <= int(end) && c != 0xFFFF; c++ {
+				glyph := uint16(c) + delta
+				if rangeOffset != 0 {
+					g, err := readUint16(table, idRangeOffsets+i*2+int(rangeOffset)+(c-int(start))*2)
+					if err != nil {
+						return err
+					}
+					glyph = 0
+					if g != 0 {
+						glyph = g + delta
+					}
+				}
+				if glyph != 0 {
+					face.setGlyph(rune(c), glyph)
+				}
+			}
+		}
+	case 6:
+		// Trimmed table mapping
+		first, err1 := readUint16(table, offset+6)
+		count, err2 := readUint16(table, offset+8)
+		if err1 != nil || err2 != nil {
+			return io.ErrUnexpectedEOF
+		}
+		for i := range int(count) {
+			glyph, err := readUint16(table, offset+10+i*2)
+			if err != nil {
+				return err
+			}
+			if glyph != 0 {
+				face.setGlyph(rune(int(first)+i), glyph)
+			}
+		}
+	case 12, 13:
+		// Segmented coverage and many-to-one range mappings
+		numGroups, err := readUint32(table, offset+12)
+		if err != nil {
+			return err
+		}
+		for i := range int(numGroups) {
+			group := offset + 16 + i*12
+			start, err1 := readUint32(table, group)
+			end, err2 := readUint32(table, group+4)
+			glyph, err3 := readUint32(table, group+8)
+			if err1 != nil || err2 != nil || err3 != nil {
+				return io.ErrUnexpectedEOF
+			}
+			if end > unicode.MaxRune || start > end {
+				return fmt.Errorf("unexpected cmap group: %d-%d", start, end)
+			}
+			for c := start; c <= end; c++ {
+				g := glyph
+				if format == 12 {
+					g += c - start
+				}
+				if g == 0 || g > 0xFFFF {
+					continue
+				}
+				face.setGlyph(rune(c), uint16(g))
+			}
+		}
+	default:
+		// Other formats (2, 8, 10, 14) are not used for unicode text
+	}
+	return nil
+}
+
+// Read unitsPerEm (head), numberOfHMetrics (hhea) and advance widths (hmtx)
+func (face *fontFace) readMetrics(data []byte, sfntOffset int) error {
+	head, _, err := findTable(data, sfntOffset, "head")
+	if err != nil {
+		return err
+	}
+	unitsPerEm, err := readUint16(data, head+18)
+	if err != nil {
+		return err
+	}
+	hhea, _, err := findTable(data, sfntOffset, "hhea")
+	if err != nil {
+		return err
+	}
+	numHMetrics, err := readUint16(data, hhea+34)
+	if err != nil {
+		return err
+	}
+	hmtx, length, err := findTable(data, sfntOffset, "hmtx")
+	if err != nil {
+		return err
+	}
+	if unitsPerEm == 0 || numHMetrics == 0 || int(numHMetrics)*4 > length {
+		return fmt.Errorf("unexpected metrics: %d units per em, %d metrics", unitsPerEm, numHMetrics)
+	}
+	face.unitsPerEm = int(unitsPerEm)
+	face.advances = make([]uint16, numHMetrics)
+	for i := range int(numHMetrics) {
+		face.advances[i], _ = readUint16(data, hmtx+i*4)
+	}
+	return nil
+}
+
+// Read horizontal pairs of kern table (format 0).
+// Kerning in GPOS is not supported.
+func (face *fontFace) readKern(data []byte, sfntOffset int) {
+	kern, length, err := findTable(data, sfntOffset, "kern")
+	if err != nil {
+		return
+	}
+	table := data[kern : kern+length]
+	version, _ := readUint16(table, 0)
+	numTables, err := readUint16(table, 2)
+	if version != 0 || err != nil {
+		return // Apple's kern table (version 1.0) is not supported
+	}
+	offset := 4
+	for range int(numTables) {
+		subLength, err1 := readUint16(table, offset+2)
+		coverage, err2 := readUint16(table, offset+4)
+		if err1 != nil || err2 != nil || subLength < 6 {
+			return
+		}
+		// Format 0, horizontal, not cross-stream and not minimum values
+		if coverage>>8 == 0 && coverage&0x7 == 1 {
+			numPairs, _ := readUint16(table, offset+6)
+			for i := range int(numPairs) {
+				pair := offset + 14 + i*6
+				left, err1 := readUint16(table, pair)
+				right, err2 := readUint16(table, pair+2)
+				value, err3 := readUint16(table, pair+4)
+				if err1 != nil || err2 != nil || err3 != nil {
+					break
+				}
+				face.kerning[uint32(left)<<16|uint32(right)] = int16(value)
+			}
+		}
+		offset += int(subLength)
+	}
+}
+
+// Check if the fonts have metrics to measure text
+func (f *Font) HasMetrics() bool {
+	for _, face := range f.faces {
+		if face.hasMetrics() {
+			return true
+		}
+	}
+	return false
+}
+
+// Find the first font that has a glyph for the character.
+// It returns the notdef glyph of the first font when not found.
+func (f *Font) findGlyph(c rune) (*fontFace, uint16) {
+	var fallback *fontFace
+	for _, face := range f.faces {
+		if !face.hasMetrics() {
+			continue
+		}
+		if glyph, ok := face.glyphs[c]; ok {
+			return face, glyph
+		}
+		if fallback == nil {
+			fallback = face
+		}
+	}
+	return fallback, 0
+}
+
+// Measure the width of a line in pixels at a point size.
+// Tags are not counted. Placeholders are measured as-is.
+func (f *Font) MeasureText(line string, pointSize float64) float64 {
+	width := 0.0
+	for _, t := range Tokenize(line) {
+		if t.Kind == TOKEN_TAG || t.Kind == TOKEN_CLOSE_TAG || t.Kind == TOKEN_LINE_BREAK {
+			continue
+		}
+		var prevFace *fontFace
+		var prevGlyph uint16
+		for _, c := range t.Raw {
+			if c == '\r' || c == '\n' {
+				continue
+			}
+			face, glyph := f.findGlyph(c)
+			if face == nil {
+				continue
+			}
+			units := face.getAdvance(glyph)
+			if face == prevFace {
+				units += int(face.kerning[uint32(prevGlyph)<<16|uint32(glyph)])
+			}
+			width += float64(units) * pointSize * FONT_DPI / 72 / float64(face.unitsPerEm)
+			prevFace, prevGlyph = face, glyph
+		}
+	}
+	return width
+}
+
+// Get a function that measures lines in pixels (rounded up)
+func (f *Font) WidthFunc(pointSize float64) WidthFunc {
+	return func(line string) int {
+		return int(math.Ceil(f.MeasureText(line, pointSize)))
+	}
+}
+
+// Entry that uses a missing glyph
+type GlyphLocation struct {
+	Path  string `json:"path"`
+	Id    string `json:"id"`
+	SubId string `json:"sub_id,omitempty"`
+}
+
+type MissingGlyph struct {
+	Rune      string          `json:"char"`
+	Code      string          `json:"code"`
+	Locations []GlyphLocation `json:"entries"`
+}
+
+type GlyphReport struct {
+	mutex   sync.Mutex
+	font    *Font
+	langs   []string // empty means all languages
+	used    map[rune]int
+	missing map[rune][]GlyphLocation
+}
+
+func NewGlyphReport(font *Font, langs []string) *GlyphReport {
+	return &GlyphReport{
+		font:    font,
+		langs:   langs,
+		used:    map[rune]int{},
+		missing: map[rune][]GlyphLocation{},
+	}
+}
+
+// Check if the glyph is needed to draw the character
+func needsGlyph(r rune) bool {
+	return !unicode.IsControl(r) && RuneWidth(r) > 0 && r != '\r' && r != '\n'
+}
+
+func (r *GlyphReport) checkText(text string, loc GlyphLocation, used map[rune]int, missing map[rune][]GlyphLocation) {
+	found := map[rune]bool{}
+	for _, t := range Tokenize(text) {
+		if t.Kind != TOKEN_TEXT && t.Kind != TOKEN_MALFORMED {
+			continue
+		}
+		for _, c := range t.Raw {
+			if !needsGlyph(c) {
+				continue
+			}
+			used[c]++
+			if !r.font.HasGlyph(c) && !found[c] {
+				found[c] = true
+				missing[c] = append(missing[c], loc)
+			}
+		}
+	}
+}
+
+// Collect characters in texts and record entries that use missing glyphs
+func (r *GlyphReport) Check(uexp *Uexp, path string) bool {
+	if len(r.langs) > 0 && !slices.Contains(r.langs, uexp.Lang) {
+		return false
+	}
+	used := map[rune]int{}
+	missing := map[rune][]GlyphLocation{}
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		if e.Text == e.Id {
+			continue
+		}
+		r.checkText(e.Text, GlyphLocation{Path: path, Id: e.Id}, used, missing)
+		for _, se := range e.SubEntries {
+			r.checkText(se.Text, GlyphLocation{Path: path, Id: e.Id, SubId: se.Id}, used, missing)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for c, count := range used {
+		r.used[c] += count
+	}
+	for c, locs := range missing {
+		r.missing[c] = append(r.missing[c], locs...)
+	}
+	return true
+}
+
+func (r *GlyphReport) CountUsed() int {
+	return len(r.used)
+}
+
+// Get missing glyphs sorted by code point
+func (r *GlyphReport) GetMissingGlyphs() []MissingGlyph {
+	runes := make([]rune, 0, len(r.missing))
+	for c := range r.missing {
+		runes = append(runes, c)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+
+	glyphs := make([]MissingGlyph, 0, len(runes))
+	for _, c := range runes {
+		locs := r.missing[c]
+		sort.SliceStable(locs, func(i, j int) bool {
+			if locs[i].Path != locs[j].Path {
+				return locs[i].Path < locs[j].Path
+			}
+			return locs[i].Id < locs[j].Id
+		})
+		glyphs = append(glyphs, MissingGlyph{Rune: string(c), Code: fmt.Sprintf("U+%04X", c), Locations: locs})
+	}
+	return glyphs
+}
+
+func (r *GlyphReport) WriteAsText(w io.Writer) {
+	glyphs := r.GetMissingGlyphs()
+	for _, g := range glyphs {
+		fmt.Fprintf(w, "%s %s: %d entries\n", g.Code, g.Rune, len(g.Locations))
+		for _, loc := range g.Locations {
+			id := loc.Id
+			if loc.SubId != "" {
+				id += " (" + loc.SubId + ")"
+			}
+			fmt.Fprintf(w, "  %s: %s\n", loc.Path, id)
+		}
+	}
+	fmt.Fprintf(w, "%d characters used, %d missing\n", r.CountUsed(), len(glyphs))
+}
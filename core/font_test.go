@@ -0,0 +1,119 @@
+package core
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func be16(values ...int) []byte {
+	b := []byte{}
+	for _, v := range values {
+		b = binary.BigEndian.AppendUint16(b, uint16(v))
+	}
+	return b
+}
+
+// Make a font that has 'A' (glyph 1) and 'V' (glyph 2).
+// Advances are 500 (notdef), 600 and 700 in 1000 units per em. A and V are kerned by -100.
+func makeTestFont() []byte {
+	// cmap format 4 with segments for A, V and the last segment
+	subtable := be16(4, 40, 0, 6, 4, 1, 2)                   // format, length, language, segCountX2, searchRange, ...
+	subtable = append(subtable, be16(0x41, 0x56, 0xFFFF)...) // endCode
+	subtable = append(subtable, be16(0)...)                  // reservedPad
+	subtable = append(subtable, be16(0x41, 0x56, 0xFFFF)...) // startCode
+	subtable = append(subtable, be16(1-0x41, 2-0x56, 1)...)  // idDelta
+	subtable = append(subtable, be16(0, 0, 0)...)            // idRangeOffset
+	cmap := append(be16(0, 1, 3, 1, 0, 12), subtable...)     // version, numTables, platform, encoding, offset
+
+	head := make([]byte, 54)
+	binary.BigEndian.PutUint16(head[18:], 1000)
+	hhea := make([]byte, 36)
+	binary.BigEndian.PutUint16(hhea[34:], 3)
+	hmtx := be16(500, 0, 600, 0, 700, 0)
+	kern := be16(0, 1, 0, 20, 0x0001, 1, 6, 0, 0, 1, 2, -100)
+
+	tables := []struct {
+		tag  string
+		data []byte
+	}{{"cmap", cmap}, {"head", head}, {"hhea", hhea}, {"hmtx", hmtx}, {"kern", kern}}
+	font := append([]byte{0, 1, 0, 0}, be16(len(tables), 0, 0, 0)...)
+	offset := 12 + len(tables)*16
+	body := []byte{}
+	for _, table := range tables {
+		font = append(font, table.tag...)
+		font = binary.BigEndian.AppendUint32(font, 0)
+		font = binary.BigEndian.AppendUint32(font, uint32(offset+len(body)))
+		font = binary.BigEndian.AppendUint32(font, uint32(len(table.data)))
+		body = append(body, table.data...)
+		for len(body)%4 != 0 {
+			body = append(body, 0)
+		}
+	}
+	return append(font, body...)
+}
+
+func loadTestFont(t *testing.T, data []byte) *Font {
+	path := filepath.Join(t.TempDir(), "test.ttf")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadFonts([]string{path})
+}
+
+func TestFontGlyphs(t *testing.T) {
+	font := loadTestFont(t, makeTestFont())
+	if font.CountGlyphs() != 2 || !font.HasGlyph('A') || !font.HasGlyph('V') || font.HasGlyph('B') {
+		t.Errorf("unexpected glyphs: %v", font.runes)
+	}
+	if !font.HasMetrics() {
+		t.Errorf("metrics not found")
+	}
+}
+
+func TestFontInAsset(t *testing.T) {
+	// Font assets have raw font data after their headers
+	data := append(bytes.Repeat([]byte{0xAB}, 37), makeTestFont()...)
+	font := loadTestFont(t, data)
+	if !font.HasGlyph('A') {
+		t.Errorf("font data in an asset not found")
+	}
+}
+
+func TestMeasureText(t *testing.T) {
+	font := loadTestFont(t, makeTestFont())
+	// 7.5pt at 96 DPI is 10px per em, so 1000 units are 10 pixels
+	tests := []struct {
+		line  string
+		width float64
+	}{
+		{"A", 6},
+		{"V", 7},
+		{"AV", 12},         // kerned
+		{"VA", 13},         // no kerning pair
+		{"A<Red>V</>", 13}, // tags split kerning pairs
+		{"B", 5},           // notdef
+		{"", 0},
+	}
+	for _, test := range tests {
+		if width := font.MeasureText(test.line, 7.5); width != test.width {
+			t.Errorf("MeasureText(%q): got %v, want %v", test.line, width, test.width)
+		}
+	}
+	if width := font.WidthFunc(7.5)("AVA"); width != 18 {
+		t.Errorf("WidthFunc: got %d, want 18", width)
+	}
+}
+
+func TestLoadBrokenFont(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.ttf")
+	os.WriteFile(path, []byte("not a font"), 0644)
+	defer func() {
+		if recover() == nil {
+			t.Errorf("broken font should throw an error")
+		}
+	}()
+	LoadFonts([]string{path})
+}
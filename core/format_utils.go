@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
-	"fmt"
 	"os"
 	"strings"
 )
@@ -25,7 +24,7 @@ func SaveAsJson(filePath string, any interface{}) {
 	}
 
 	// Open or create a file for writing
-	fmt.Printf("Writing %s...\n", filePath)
+	Printf("Writing %s...\n", filePath)
 	file := CreateFile(filePath)
 	defer file.Close()
 
@@ -37,7 +36,7 @@ func SaveAsJson(filePath string, any interface{}) {
 }
 
 func LoadFromJson(filePath string, any interface{}) {
-	fmt.Printf("Reading %s...\n", filePath)
+	Printf("Reading %s...\n", filePath)
 	jsonData, err := os.ReadFile(filePath)
 	if err != nil {
 		Throw(err)
@@ -60,7 +59,7 @@ func CsvStrToGoStr(str string) string {
 
 func LoadFromCsv(filePath string, obj CsvSupported) {
 	// Open or create a file for writing
-	fmt.Printf("Reading %s...\n", filePath)
+	Printf("Reading %s...\n", filePath)
 	file := OpenFile(filePath)
 	defer file.Close()
 
@@ -70,7 +69,7 @@ func LoadFromCsv(filePath string, obj CsvSupported) {
 
 func SaveAsCsv(filePath string, obj CsvSupported) {
 	// Open or create a file for writing
-	fmt.Printf("Writing %s...\n", filePath)
+	Printf("Writing %s...\n", filePath)
 	file := CreateFile(filePath)
 	defer file.Close()
 
@@ -0,0 +1,248 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+type GlossaryTerm struct {
+	Source  string   `json:"source"`
+	Targets []string `json:"targets"` // approved translations
+}
+
+// Load terms from csv.
+// Each row has a source form and approved target forms separated by "|".
+//
+//	source,target
+//	Materia,マテリア
+//	Sephiroth,セフィロス|Sephiroth
+func LoadGlossary(filePath string) []GlossaryTerm {
+	Printf("Reading %s...\n", filePath)
+	file := OpenFile(filePath)
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	terms := []GlossaryTerm{}
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			Throw(err)
+		} else if len(row) < 2 {
+			Throw("each row should has 2 items in glossary")
+		}
+		if row[0] == "source" || row[0] == "" {
+			continue // header or empty row
+		}
+		terms = append(terms, GlossaryTerm{Source: row[0], Targets: strings.Split(row[1], "|")})
+	}
+	return terms
+}
+
+type GlossaryIssue struct {
+	Path    string `json:"path"`
+	Id      string `json:"id"`
+	SubId   string `json:"sub_id,omitempty"`
+	Term    string `json:"term"`
+	Message string `json:"message"`
+}
+
+func (issue *GlossaryIssue) String() string {
+	id := issue.Id
+	if issue.SubId != "" {
+		id += " (" + issue.SubId + ")"
+	}
+	return fmt.Sprintf("%s: %s: %s", issue.Path, id, issue.Message)
+}
+
+type actorLocation struct {
+	path string
+	id   string
+}
+
+type GlossaryReport struct {
+	mutex  sync.Mutex
+	terms  []GlossaryTerm
+	actors map[string]map[string][]actorLocation // source actor -> target actor -> entries
+	Issues []GlossaryIssue
+}
+
+func NewGlossaryReport(terms []GlossaryTerm) *GlossaryReport {
+	return &GlossaryReport{
+		terms:  terms,
+		actors: map[string]map[string][]actorLocation{},
+		Issues: []GlossaryIssue{},
+	}
+}
+
+// Scripts that separate words with spaces.
+// Terms of these scripts should not match parts of words (e.g. "Ether" in "Ethereal").
+var WORD_BOUNDARY_SCRIPTS = []*unicode.RangeTable{unicode.Latin, unicode.Greek, unicode.Cyrillic}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+func needsWordBoundary(r rune) bool {
+	return unicode.IsDigit(r) || unicode.IsOneOf(WORD_BOUNDARY_SCRIPTS, r)
+}
+
+// Find a term in text (case-insensitive).
+// Edges of Latin, Greek and Cyrillic terms should be on word boundaries.
+func containsTerm(text string, term string) bool {
+	text = strings.ToLower(text)
+	term = strings.ToLower(term)
+	if term == "" {
+		return false
+	}
+	first, _ := utf8.DecodeRuneInString(term)
+	last, _ := utf8.DecodeLastRuneInString(term)
+	for offset := 0; offset < len(text); {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+		start := offset + i
+		end := start + len(term)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if (start == 0 || !needsWordBoundary(first) || !isWordRune(before)) &&
+			(end == len(text) || !needsWordBoundary(last) || !isWordRune(after)) {
+			return true
+		}
+		_, size := utf8.DecodeRuneInString(text[start:])
+		offset = start + size
+	}
+	return false
+}
+
+func (r *GlossaryReport) checkText(issues []GlossaryIssue, path string, id string, subId string, src string, dst string) []GlossaryIssue {
+	for _, term := range r.terms {
+		if !containsTerm(src, term.Source) {
+			continue
+		}
+		found := false
+		for _, target := range term.Targets {
+			if strings.Contains(dst, target) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			issues = append(issues, GlossaryIssue{
+				Path:    path,
+				Id:      id,
+				SubId:   subId,
+				Term:    term.Source,
+				Message: fmt.Sprintf("%q should be translated as %q", term.Source, strings.Join(term.Targets, "\" or \"")),
+			})
+		}
+	}
+	return issues
+}
+
+// Check an aligned pair of assets. path is used for locations in the report.
+func (r *GlossaryReport) Check(src *Uexp, dst *Uexp, path string) {
+	issues := []GlossaryIssue{}
+	type actorPair struct {
+		src string
+		dst string
+		id  string
+	}
+	actors := []actorPair{}
+	for i := range len(src.Entries) {
+		e := &src.Entries[i]
+		j := dst.FindEntry(e.Id, min(i, len(dst.Entries)-1))
+		if j < 0 {
+			continue
+		}
+		e2 := &dst.Entries[j]
+		issues = r.checkText(issues, path, e.Id, "", e.Text, e2.Text)
+		for _, se := range e.SubEntries {
+			for _, se2 := range e2.SubEntries {
+				if se.Id != se2.Id {
+					continue
+				}
+				issues = r.checkText(issues, path, e.Id, se.Id, se.Text, se2.Text)
+				if se.Id == "ACTOR" && se.Text != "" {
+					actors = append(actors, actorPair{se.Text, se2.Text, e.Id})
+				}
+				break
+			}
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Issues = append(r.Issues, issues...)
+	for _, a := range actors {
+		targets, ok := r.actors[a.src]
+		if !ok {
+			targets = map[string][]actorLocation{}
+			r.actors[a.src] = targets
+		}
+		targets[a.dst] = append(targets[a.dst], actorLocation{path, a.id})
+	}
+}
+
+// Report ACTOR names that are translated in more than one way
+func (r *GlossaryReport) CheckActors() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	sources := make([]string, 0, len(r.actors))
+	for src := range r.actors {
+		sources = append(sources, src)
+	}
+	sort.Strings(sources)
+	for _, src := range sources {
+		targets := r.actors[src]
+		if len(targets) <= 1 {
+			continue
+		}
+		names := make([]string, 0, len(targets))
+		for name := range targets {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			locs := targets[name]
+			sort.Slice(locs, func(i, j int) bool {
+				if locs[i].path != locs[j].path {
+					return locs[i].path < locs[j].path
+				}
+				return locs[i].id < locs[j].id
+			})
+			r.Issues = append(r.Issues, GlossaryIssue{
+				Path:    locs[0].path,
+				Id:      locs[0].id,
+				SubId:   "ACTOR",
+				Term:    src,
+				Message: fmt.Sprintf("ACTOR %q is translated as %q in %d entries (%d variants)", src, name, len(locs), len(names)),
+			})
+		}
+	}
+}
+
+func (r *GlossaryReport) Sort() {
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		if r.Issues[i].Path != r.Issues[j].Path {
+			return r.Issues[i].Path < r.Issues[j].Path
+		}
+		return r.Issues[i].Id < r.Issues[j].Id
+	})
+}
+
+func (r *GlossaryReport) WriteAsText(w io.Writer) {
+	for i := range len(r.Issues) {
+		fmt.Fprintln(w, r.Issues[i].String())
+	}
+	fmt.Fprintf(w, "%d glossary issues\n", len(r.Issues))
+}
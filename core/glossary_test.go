@@ -0,0 +1,50 @@
+package core
+
+import "testing"
+
+func TestContainsTerm(t *testing.T) {
+	tests := []struct {
+		text  string
+		term  string
+		found bool
+	}{
+		{"Use an Ether.", "Ether", true},
+		{"use an ether", "Ether", true},
+		{"An Ethereal light", "Ether", false},
+		{"Hi-Ether", "Ether", true},
+		{"Ethereal Ether", "Ether", true}, // the second one is a word
+		{"Materia's power", "Materia", true},
+		{"Fire2", "Fire", false},
+		{"エーテルを使う", "エーテル", true},
+		{"마테리아를", "마테리아", true},
+		{"Éther", "éther", true},
+		{"anything", "", false},
+	}
+	for _, test := range tests {
+		if found := containsTerm(test.text, test.term); found != test.found {
+			t.Errorf("containsTerm(%q, %q): got %v, want %v", test.text, test.term, found, test.found)
+		}
+	}
+}
+
+func TestGlossaryCheck(t *testing.T) {
+	report := NewGlossaryReport([]GlossaryTerm{{Source: "Materia", Targets: []string{"マテリア"}}})
+	src := &Uexp{Entries: []Entry{
+		{Id: "a", Text: "Equip Materia.", SubEntries: []SubEntry{{Id: "ACTOR", Text: "Cloud"}}},
+		{Id: "b", Text: "Materials", SubEntries: []SubEntry{{Id: "ACTOR", Text: "Cloud"}}},
+	}}
+	dst := &Uexp{Entries: []Entry{
+		{Id: "a", Text: "まてりあを装備", SubEntries: []SubEntry{{Id: "ACTOR", Text: "クラウド"}}},
+		{Id: "b", Text: "素材", SubEntries: []SubEntry{{Id: "ACTOR", Text: "クラウド?"}}},
+	}}
+	report.Check(src, dst, "Text/Story")
+	report.CheckActors()
+	if len(report.Issues) != 3 || report.Issues[0].Id != "a" || report.Issues[0].Term != "Materia" {
+		t.Errorf("unexpected issues: %v", report.Issues)
+	}
+	for _, issue := range report.Issues[1:] {
+		if issue.SubId != "ACTOR" {
+			t.Errorf("unexpected issue: %v", issue)
+		}
+	}
+}
@@ -0,0 +1,419 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var LINT_SEVERITY_LIST = []string{
+	"off",
+	"note",
+	"warning",
+	"error",
+}
+
+type LintRuleConfig struct {
+	Severity string `json:"severity"`
+	Max      int    `json:"max,omitempty"` // used by max_lines, max_width and max_pixel_width
+
+	measure WidthFunc // used by max_pixel_width
+}
+
+type LintConfig struct {
+	Rules map[string]*LintRuleConfig `json:"rules"`
+}
+
+// A lint rule checks a text of an entry or a sub entry.
+// se is nil when the text is the main text of the entry.
+type LintRule struct {
+	Id          string
+	Description string
+	Severity    string // default severity
+	Max         int    // default parameter
+	Check       func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string
+}
+
+var LINT_RULES = []LintRule{
+	{
+		Id:          "max_lines",
+		Description: "Subtitle has too many lines.",
+		Severity:    "warning",
+		Max:         3,
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			if se != nil || !e.IsSubtitle() {
+				return nil
+			}
+			lines := e.CountLines()
+			if lines > config.Max {
+				return []string{fmt.Sprintf("subtitle has %d lines (max: %d)", lines, config.Max)}
+			}
+			return nil
+		},
+	},
+	{
+		Id:          "max_width",
+		Description: "Subtitle line is too wide.",
+		Severity:    "warning",
+		Max:         68,
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			if se != nil || !e.IsSubtitle() || text == e.Id {
+				return nil
+			}
+			messages := []string{}
+			for i, line := range strings.Split(text, "\r\n") {
+				width := TextWidth(line)
+				if width > config.Max {
+					messages = append(messages, fmt.Sprintf("line %d is %d wide (max: %d)", i+1, width, config.Max))
+				}
+			}
+			return messages
+		},
+	},
+	{
+		Id:          "max_pixel_width",
+		Description: "Subtitle line is wider than the subtitle widget.",
+		Severity:    "warning",
+		Max:         0, // the width of the subtitle widget by default (see SetMeasure)
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			if config.measure == nil || se != nil || !e.IsSubtitle() || text == e.Id {
+				return nil // it needs fonts
+			}
+			messages := []string{}
+			for i, line := range strings.Split(text, "\r\n") {
+				width := config.measure(line)
+				if width > config.Max {
+					messages = append(messages, fmt.Sprintf("line %d is %d px wide (max: %d px)", i+1, width, config.Max))
+				}
+			}
+			return messages
+		},
+	},
+	{
+		Id:          "double_space",
+		Description: "Text contains doubled spaces.",
+		Severity:    "warning",
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			if strings.Contains(text, "  ") {
+				return []string{"text contains doubled spaces"}
+			}
+			return nil
+		},
+	},
+	{
+		Id:          "trailing_space",
+		Description: "Line ends with whitespace.",
+		Severity:    "warning",
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			messages := []string{}
+			for i, line := range strings.Split(text, "\n") {
+				line = strings.TrimSuffix(line, "\r")
+				if len(strings.TrimRight(line, " \t　")) != len(line) {
+					messages = append(messages, fmt.Sprintf("line %d ends with whitespace", i+1))
+				}
+			}
+			return messages
+		},
+	},
+	{
+		Id:          "bare_lf",
+		Description: "Line feed is not \\r\\n.",
+		Severity:    "error",
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			bare := strings.Count(text, "\n") - strings.Count(text, "\r\n")
+			if bare > 0 {
+				return []string{fmt.Sprintf("text contains %d bare \\n (use \\r\\n)", bare)}
+			}
+			return nil
+		},
+	},
+	{
+		Id:          "text_is_id",
+		Description: "Text is identical to the entry id.",
+		Severity:    "note",
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			if se == nil && text == e.Id {
+				return []string{"text is identical to the entry id"}
+			}
+			return nil
+		},
+	},
+	{
+		Id:          "empty_text",
+		Description: "Text is empty.",
+		Severity:    "note",
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			if text == "" {
+				return []string{"text is empty"}
+			}
+			return nil
+		},
+	},
+	{
+		Id:          "malformed_markup",
+		Description: "Tag or placeholder is not closed.",
+		Severity:    "error",
+		Check: func(e *Entry, se *SubEntry, text string, config *LintRuleConfig) []string {
+			messages := []string{}
+			for _, t := range Tokenize(text) {
+				if t.Kind == TOKEN_MALFORMED {
+					messages = append(messages, fmt.Sprintf("malformed markup (%q)", t.Raw))
+				}
+			}
+			return messages
+		},
+	},
+}
+
+func GetLintRule(id string) *LintRule {
+	for i := range len(LINT_RULES) {
+		if LINT_RULES[i].Id == id {
+			return &LINT_RULES[i]
+		}
+	}
+	return nil
+}
+
+// Get config with default severities
+func NewLintConfig() *LintConfig {
+	config := &LintConfig{Rules: map[string]*LintRuleConfig{}}
+	for _, rule := range LINT_RULES {
+		config.Rules[rule.Id] = &LintRuleConfig{Severity: rule.Severity, Max: rule.Max}
+	}
+	return config
+}
+
+// Load config from json. Missing rules and parameters use the default values.
+func LoadLintConfig(filePath string) *LintConfig {
+	config := NewLintConfig()
+	if filePath == "" {
+		return config
+	}
+
+	Printf("Reading %s...\n", filePath)
+	jsonData, err := os.ReadFile(filePath)
+	if err != nil {
+		Throw(err)
+	}
+	newConfig := &LintConfig{}
+	if err := json.Unmarshal(jsonData, newConfig); err != nil {
+		Throw(err)
+	}
+
+	for id, newRule := range newConfig.Rules {
+		rule, ok := config.Rules[id]
+		if !ok {
+			Throw(fmt.Errorf("unknown lint rule detected. (%s)", id))
+		}
+		if newRule.Severity != "" {
+			if !slices.Contains(LINT_SEVERITY_LIST, newRule.Severity) {
+				Throw(fmt.Errorf("unknown severity detected. (%s: %s)", id, newRule.Severity))
+			}
+			rule.Severity = newRule.Severity
+		}
+		if newRule.Max > 0 {
+			rule.Max = newRule.Max
+		}
+	}
+	return config
+}
+
+// Set a function that measures lines in pixels for max_pixel_width.
+// widgetWidth is used as the max width unless the config has it.
+func (c *LintConfig) SetMeasure(measure WidthFunc, widgetWidth int) {
+	rule := c.Rules["max_pixel_width"]
+	rule.measure = measure
+	if rule.Max == 0 {
+		rule.Max = widgetWidth
+	}
+}
+
+type LintIssue struct {
+	RuleId   string `json:"rule"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Id       string `json:"id"`
+	SubId    string `json:"sub_id,omitempty"`
+	Message  string `json:"message"`
+}
+
+func (issue *LintIssue) String() string {
+	id := issue.Id
+	if issue.SubId != "" {
+		id += " (" + issue.SubId + ")"
+	}
+	return fmt.Sprintf("%s: %s: %s: %s [%s]", issue.Path, id, issue.Severity, issue.Message, issue.RuleId)
+}
+
+type LintReport struct {
+	mutex  sync.Mutex
+	config *LintConfig
+	Issues []LintIssue
+}
+
+func NewLintReport(config *LintConfig) *LintReport {
+	return &LintReport{config: config, Issues: []LintIssue{}}
+}
+
+func (r *LintReport) lintText(issues []LintIssue, path string, e *Entry, se *SubEntry, text string) []LintIssue {
+	for _, rule := range LINT_RULES {
+		config := r.config.Rules[rule.Id]
+		if config.Severity == "off" {
+			continue
+		}
+		for _, msg := range rule.Check(e, se, text, config) {
+			issue := LintIssue{
+				RuleId:   rule.Id,
+				Severity: config.Severity,
+				Path:     path,
+				Id:       e.Id,
+				Message:  msg,
+			}
+			if se != nil {
+				issue.SubId = se.Id
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
+
+// Run lint rules over entries. path is used for locations in the report.
+func (r *LintReport) Lint(uexp *Uexp, path string) {
+	issues := []LintIssue{}
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		issues = r.lintText(issues, path, e, nil, e.Text)
+		for j := range len(e.SubEntries) {
+			issues = r.lintText(issues, path, e, &e.SubEntries[j], e.SubEntries[j].Text)
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Issues = append(r.Issues, issues...)
+}
+
+// Sort issues by path and entry id since workers add them in random order
+func (r *LintReport) Sort() {
+	sort.SliceStable(r.Issues, func(i, j int) bool {
+		if r.Issues[i].Path != r.Issues[j].Path {
+			return r.Issues[i].Path < r.Issues[j].Path
+		}
+		return r.Issues[i].Id < r.Issues[j].Id
+	})
+}
+
+func (r *LintReport) CountSeverity(severity string) int {
+	count := 0
+	for _, issue := range r.Issues {
+		if issue.Severity == severity {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *LintReport) WriteAsText(w io.Writer) {
+	for i := range len(r.Issues) {
+		fmt.Fprintln(w, r.Issues[i].String())
+	}
+	fmt.Fprintf(w, "%d errors, %d warnings, %d notes\n",
+		r.CountSeverity("error"), r.CountSeverity("warning"), r.CountSeverity("note"))
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	Id               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+	DefaultConfig    struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+}
+
+type sarifLogicalLocation struct {
+	Name               string `json:"name"`
+	FullyQualifiedName string `json:"fullyQualifiedName"`
+	Kind               string `json:"kind"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			Uri string `json:"uri"`
+		} `json:"artifactLocation"`
+	} `json:"physicalLocation"`
+	LogicalLocations []sarifLogicalLocation `json:"logicalLocations"`
+}
+
+type sarifResult struct {
+	RuleId    string          `json:"ruleId"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name    string      `json:"name"`
+			Version string      `json:"version"`
+			Rules   []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// Convert the report to SARIF 2.1.0
+func (r *LintReport) ToSarif(toolName string, toolVersion string) interface{} {
+	run := sarifRun{}
+	run.Tool.Driver.Name = toolName
+	run.Tool.Driver.Version = toolVersion
+	run.Tool.Driver.Rules = []sarifRule{}
+	for _, rule := range LINT_RULES {
+		sr := sarifRule{Id: rule.Id, ShortDescription: sarifMessage{Text: rule.Description}}
+		sr.DefaultConfig.Level = rule.Severity
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sr)
+	}
+
+	run.Results = make([]sarifResult, 0, len(r.Issues))
+	for _, issue := range r.Issues {
+		name := issue.Id
+		if issue.SubId != "" {
+			name += "/" + issue.SubId
+		}
+		loc := sarifLocation{
+			LogicalLocations: []sarifLogicalLocation{
+				{Name: issue.Id, FullyQualifiedName: name, Kind: "member"},
+			},
+		}
+		loc.PhysicalLocation.ArtifactLocation.Uri = filepath.ToSlash(issue.Path)
+		run.Results = append(run.Results, sarifResult{
+			RuleId:    issue.RuleId,
+			Level:     issue.Severity,
+			Message:   sarifMessage{Text: issue.Message},
+			Locations: []sarifLocation{loc},
+		})
+	}
+
+	return &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}
+}
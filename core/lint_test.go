@@ -0,0 +1,178 @@
+package core
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// Lint an entry and get rule ids of issues
+func lintRules(config *LintConfig, e Entry) []string {
+	report := NewLintReport(config)
+	report.Lint(&Uexp{Entries: []Entry{e}}, "Text/Story")
+	rules := []string{}
+	for _, issue := range report.Issues {
+		rules = append(rules, issue.RuleId)
+	}
+	return rules
+}
+
+func TestLintRules(t *testing.T) {
+	actor := []SubEntry{{Id: "ACTOR", Text: "Cloud"}}
+	tests := []struct {
+		entry Entry
+		rules []string
+	}{
+		{Entry{Id: "a", Text: "Let's go.", SubEntries: actor}, []string{}},
+		{Entry{Id: "a", Text: "a\r\nb\r\nc\r\nd", SubEntries: actor}, []string{"max_lines"}},
+		{Entry{Id: "a", Text: "a\r\nb\r\nc\r\nd"}, []string{}}, // not a subtitle
+		{Entry{Id: "a", Text: strings.Repeat("a", 69), SubEntries: actor}, []string{"max_width"}},
+		{Entry{Id: "a", Text: strings.Repeat("あ", 35), SubEntries: actor}, []string{"max_width"}},
+		{Entry{Id: "a", Text: strings.Repeat("a", 68), SubEntries: actor}, []string{}},
+		{Entry{Id: "a", Text: "a  b"}, []string{"double_space"}},
+		{Entry{Id: "a", Text: "a \r\nb　"}, []string{"trailing_space", "trailing_space"}},
+		{Entry{Id: "a", Text: "a\nb"}, []string{"bare_lf"}},
+		{Entry{Id: "a", Text: "a"}, []string{"text_is_id"}},
+		{Entry{Id: "a", Text: ""}, []string{"empty_text"}},
+		{Entry{Id: "a", Text: "<Red>Cloud"}, []string{}},
+		{Entry{Id: "a", Text: "<Red Cloud"}, []string{"malformed_markup"}},
+		// Sub entries are checked too
+		{Entry{Id: "a", Text: "b", SubEntries: []SubEntry{{Id: "ACTOR", Text: ""}}}, []string{"empty_text"}},
+	}
+	for _, test := range tests {
+		if rules := lintRules(NewLintConfig(), test.entry); !slices.Equal(rules, test.rules) {
+			t.Errorf("Lint(%q): got %v, want %v", test.entry.Text, rules, test.rules)
+		}
+	}
+}
+
+func TestLintPixelWidth(t *testing.T) {
+	actor := []SubEntry{{Id: "ACTOR", Text: "Cloud"}}
+	e := Entry{Id: "a", Text: "abcdef", SubEntries: actor}
+
+	// It needs fonts
+	config := NewLintConfig()
+	if rules := lintRules(config, e); len(rules) != 0 {
+		t.Errorf("max_pixel_width without fonts: got %v", rules)
+	}
+
+	// 10 pixels per rune
+	measure := func(line string) int {
+		return utf8.RuneCountInString(line) * 10
+	}
+	config.SetMeasure(measure, 50)
+	if rules := lintRules(config, e); !slices.Equal(rules, []string{"max_pixel_width"}) {
+		t.Errorf("max_pixel_width: got %v", rules)
+	}
+
+	// The config has priority over the widget width
+	config = NewLintConfig()
+	config.Rules["max_pixel_width"].Max = 60
+	config.SetMeasure(measure, 50)
+	if rules := lintRules(config, e); len(rules) != 0 {
+		t.Errorf("max_pixel_width with max: got %v", rules)
+	}
+}
+
+func TestLoadLintConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lint.json")
+	data := `{"rules": {"double_space": {"severity": "off"}, "max_lines": {"max": 2}, "text_is_id": {"severity": "error"}}}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := LoadLintConfig(path)
+	if config.Rules["double_space"].Severity != "off" {
+		t.Errorf("severity of double_space: got %s", config.Rules["double_space"].Severity)
+	}
+	if rule := config.Rules["max_lines"]; rule.Max != 2 || rule.Severity != "warning" {
+		t.Errorf("max_lines: got %+v", rule)
+	}
+	if rule := config.Rules["max_width"]; rule.Max != 68 {
+		t.Errorf("max of max_width: got %d", rule.Max)
+	}
+
+	e := Entry{Id: "a", Text: "a  b\r\nc\r\nd", SubEntries: []SubEntry{{Id: "ACTOR", Text: "Cloud"}}}
+	if rules := lintRules(config, e); !slices.Equal(rules, []string{"max_lines"}) {
+		t.Errorf("Lint with config: got %v", rules)
+	}
+	report := NewLintReport(config)
+	report.Lint(&Uexp{Entries: []Entry{{Id: "a", Text: "a"}}}, "Text/Story")
+	if report.CountSeverity("error") != 1 {
+		t.Errorf("severity of text_is_id: got %v", report.Issues)
+	}
+
+	for _, data := range []string{
+		`{"rules": {"unknown_rule": {"severity": "off"}}}`,
+		`{"rules": {"double_space": {"severity": "fatal"}}}`,
+	} {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := Try(func() { LoadLintConfig(path) }); err == nil {
+			t.Errorf("LoadLintConfig(%s) should throw an error", data)
+		}
+	}
+}
+
+func TestLintToSarif(t *testing.T) {
+	report := NewLintReport(NewLintConfig())
+	report.Lint(&Uexp{Entries: []Entry{
+		{Id: "a", Text: "b", SubEntries: []SubEntry{{Id: "ACTOR", Text: "<Red"}}},
+	}}, filepath.Join("Text", "Story"))
+	data, err := json.Marshal(report.ToSarif("ff7r-text-tool", "1.0"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sarif := struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Name  string `json:"name"`
+					Rules []struct {
+						Id string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleId    string `json:"ruleId"`
+				Level     string `json:"level"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							Uri string `json:"uri"`
+						} `json:"artifactLocation"`
+					} `json:"physicalLocation"`
+					LogicalLocations []struct {
+						FullyQualifiedName string `json:"fullyQualifiedName"`
+					} `json:"logicalLocations"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}{}
+	if err := json.Unmarshal(data, &sarif); err != nil {
+		t.Fatal(err)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 {
+		t.Fatalf("unexpected sarif: %s", data)
+	}
+	run := sarif.Runs[0]
+	if run.Tool.Driver.Name != "ff7r-text-tool" || len(run.Tool.Driver.Rules) != len(LINT_RULES) {
+		t.Errorf("unexpected driver: %+v", run.Tool.Driver)
+	}
+	if len(run.Results) != 1 {
+		t.Fatalf("unexpected results: %s", data)
+	}
+	result := run.Results[0]
+	loc := result.Locations[0]
+	if result.RuleId != "malformed_markup" || result.Level != "error" ||
+		loc.PhysicalLocation.ArtifactLocation.Uri != "Text/Story" ||
+		loc.LogicalLocations[0].FullyQualifiedName != "a/ACTOR" {
+		t.Errorf("unexpected result: %+v", result)
+	}
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Property types in the order of EPropertyType of usmap files
+var USMAP_PROPERTY_TYPES = []string{
+	"ByteProperty", "BoolProperty", "IntProperty", "FloatProperty",
+	"ObjectProperty", "NameProperty", "DelegateProperty", "DoubleProperty",
+	"ArrayProperty", "StructProperty", "StrProperty", "TextProperty",
+	"InterfaceProperty", "MulticastDelegateProperty", "WeakObjectProperty", "LazyObjectProperty",
+	"AssetObjectProperty", "SoftObjectProperty", "UInt64Property", "UInt32Property",
+	"UInt16Property", "Int64Property", "Int16Property", "Int8Property",
+	"MapProperty", "SetProperty", "EnumProperty", "FieldPathProperty",
+}
+
+var USMAP_MAGIC = uint16(0x30C4)
+
+// Versions of usmap files
+const (
+	USMAP_VER_INITIAL = iota
+	USMAP_VER_PACKAGE_VERSIONING
+	USMAP_VER_LONG_FNAME
+	USMAP_VER_LARGE_ENUMS
+)
+
+// Type of a property in mappings
+type PropertyType struct {
+	Type       string        `json:"type"`
+	StructName string        `json:"struct,omitempty"` // for StructProperty
+	EnumName   string        `json:"enum,omitempty"`   // for EnumProperty and ByteProperty
+	Inner      *PropertyType `json:"inner,omitempty"`  // for ArrayProperty, SetProperty and EnumProperty, or the key of MapProperty
+	Value      *PropertyType `json:"value,omitempty"`  // for MapProperty
+}
+
+type SchemaProperty struct {
+	Index     int          `json:"index"` // schema index in the struct (without super structs)
+	ArraySize int          `json:"array_size,omitempty"`
+	Name      string       `json:"name"`
+	Type      PropertyType `json:"type"`
+}
+
+// Properties of a class or a struct
+type Schema struct {
+	Super         string           `json:"super,omitempty"`
+	PropertyCount int              `json:"property_count,omitempty"` // number of schema indices including static arrays
+	Properties    []SchemaProperty `json:"properties"`
+}
+
+// Schemas of classes and structs for unversioned properties.
+// It can be loaded from a usmap file (the format FModel uses) or a json file.
+type Mappings struct {
+	Enums   map[string][]string `json:"enums"`
+	Schemas map[string]*Schema  `json:"schemas"`
+}
+
+// Load mappings from .usmap or .json
+func LoadMappings(filePath string) *Mappings {
+	Printf("Reading %s...\n", filePath)
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		Throw(err)
+	}
+	m := &Mappings{}
+	if strings.ToLower(filepath.Ext(filePath)) == ".json" {
+		if err := json.Unmarshal(data, m); err != nil {
+			Throw(fmt.Errorf("failed to parse mappings: %v (%s)", err, filePath))
+		}
+	} else if err := m.readUsmap(data); err != nil {
+		Throw(fmt.Errorf("%v (%s)", err, filePath))
+	}
+	if err := m.init(); err != nil {
+		Throw(fmt.Errorf("%v (%s)", err, filePath))
+	}
+	return m
+}
+
+// Fill default values and validate schemas
+func (m *Mappings) init() error {
+	if m.Enums == nil {
+		m.Enums = map[string][]string{}
+	}
+	if m.Schemas == nil {
+		m.Schemas = map[string]*Schema{}
+	}
+	for name, schema := range m.Schemas {
+		count := 0
+		for i := range len(schema.Properties) {
+			prop := &schema.Properties[i]
+			if prop.ArraySize <= 0 {
+				prop.ArraySize = 1
+			}
+			count = max(count, prop.Index+prop.ArraySize)
+		}
+		if schema.PropertyCount == 0 {
+			schema.PropertyCount = count
+		} else if schema.PropertyCount < count {
+			return fmt.Errorf("unexpected property count detected. (%s, %d < %d)", name, schema.PropertyCount, count)
+		}
+		if schema.Super != "" && m.Schemas[schema.Super] == nil {
+			return fmt.Errorf("super struct not found in mappings. (%s: %s)", name, schema.Super)
+		}
+	}
+	for name := range m.Schemas {
+		visited := map[string]bool{}
+		for s := name; s != ""; s = m.Schemas[s].Super {
+			if visited[s] {
+				return fmt.Errorf("circular super struct detected. (%s)", name)
+			}
+			visited[s] = true
+		}
+	}
+	return nil
+}
+
+func (m *Mappings) readUsmap(data []byte) error {
+	r := &byteReader{data: data}
+	if r.uint16() != USMAP_MAGIC {
+		return fmt.Errorf("not a usmap file")
+	}
+	version := int(r.uint8())
+	if version > USMAP_VER_LARGE_ENUMS {
+		return fmt.Errorf("unsupported usmap version: %d", version)
+	}
+	if version >= USMAP_VER_PACKAGE_VERSIONING && r.int32() != 0 {
+		r.read(8) // FileVersionUE4, FileVersionUE5
+		customVersions := r.int32()
+		r.read(int(customVersions) * 20)
+		r.read(4) // NetCL
+	}
+	if r.uint8() != 0 {
+		return fmt.Errorf("compressed usmap is not supported. decompress it with FModel or use a json file")
+	}
+	compSize, decompSize := r.uint32(), r.uint32()
+	if r.err != nil {
+		return r.err
+	}
+	if compSize != decompSize || int(compSize) != len(data)-r.offset {
+		return fmt.Errorf("unexpected usmap size: %d", compSize)
+	}
+
+	nameCount := int(r.uint32())
+	names := make([]string, 0, min(nameCount, 1<<16))
+	for range nameCount {
+		var length int
+		if version >= USMAP_VER_LONG_FNAME {
+			length = int(r.uint16())
+		} else {
+			length = int(r.uint8())
+		}
+		names = append(names, string(r.read(length)))
+		if r.err != nil {
+			return r.err
+		}
+	}
+	readName := func() string {
+		i := r.uint32()
+		if i == 0xFFFFFFFF {
+			return ""
+		} else if int(i) >= len(names) {
+			if r.err == nil {
+				r.err = fmt.Errorf("unexpected name index: %d", i)
+			}
+			return ""
+		}
+		return names[i]
+	}
+
+	m.Enums = map[string][]string{}
+	enumCount := int(r.uint32())
+	for range enumCount {
+		name := readName()
+		var count int
+		if version >= USMAP_VER_LARGE_ENUMS {
+			count = int(r.uint16())
+		} else {
+			count = int(r.uint8())
+		}
+		values := make([]string, 0, count)
+		for range count {
+			values = append(values, readName())
+		}
+		m.Enums[name] = values
+		if r.err != nil {
+			return r.err
+		}
+	}
+
+	m.Schemas = map[string]*Schema{}
+	structCount := int(r.uint32())
+	for range structCount {
+		name := readName()
+		schema := &Schema{Super: readName()}
+		schema.PropertyCount = int(r.uint16())
+		serializableCount := int(r.uint16())
+		for range serializableCount {
+			prop := SchemaProperty{}
+			prop.Index = int(r.uint16())
+			prop.ArraySize = int(r.uint8())
+			prop.Name = readName()
+			prop.Type = *readUsmapType(r, readName)
+			schema.Properties = append(schema.Properties, prop)
+		}
+		if r.err != nil {
+			return r.err
+		}
+		m.Schemas[name] = schema
+	}
+	return r.err
+}
+
+func readUsmapType(r *byteReader, readName func() string) *PropertyType {
+	id := int(r.uint8())
+	if id >= len(USMAP_PROPERTY_TYPES) {
+		if r.err == nil {
+			r.err = fmt.Errorf("unknown property type: %d", id)
+		}
+		return &PropertyType{}
+	}
+	t := &PropertyType{Type: USMAP_PROPERTY_TYPES[id]}
+	switch t.Type {
+	case "EnumProperty":
+		t.Inner = readUsmapType(r, readName)
+		t.EnumName = readName()
+	case "StructProperty":
+		t.StructName = readName()
+	case "ArrayProperty", "SetProperty":
+		t.Inner = readUsmapType(r, readName)
+	case "MapProperty":
+		t.Inner = readUsmapType(r, readName)
+		t.Value = readUsmapType(r, readName)
+	}
+	return t
+}
+
+// Property at a schema index. Super structs come first.
+type flatProperty struct {
+	name string // "Name" or "Name[i]" for static arrays
+	typ  *PropertyType
+}
+
+// Get properties of a struct and its super structs by schema index
+func (m *Mappings) getFlatProperties(structName string) ([]*flatProperty, error) {
+	schema, ok := m.Schemas[structName]
+	if !ok {
+		return nil, fmt.Errorf("struct not found in mappings. (%s)", structName)
+	}
+	props := []*flatProperty{}
+	if schema.Super != "" {
+		super, err := m.getFlatProperties(schema.Super)
+		if err != nil {
+			return nil, err
+		}
+		props = append(props, super...)
+	}
+	start := len(props)
+	props = append(props, make([]*flatProperty, schema.PropertyCount)...)
+	for i := range len(schema.Properties) {
+		prop := &schema.Properties[i]
+		for j := range prop.ArraySize {
+			name := prop.Name
+			if j > 0 {
+				name = fmt.Sprintf("%s[%d]", prop.Name, j)
+			}
+			props[start+prop.Index+j] = &flatProperty{name: name, typ: &prop.Type}
+		}
+	}
+	return props, nil
+}
@@ -0,0 +1,288 @@
+package core
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type TokenKind int
+
+const (
+	TOKEN_TEXT        TokenKind = iota // literal run
+	TOKEN_LINE_BREAK                   // \r\n or \n
+	TOKEN_TAG                          // <tag>, <tag attr="x">, <tag/>
+	TOKEN_CLOSE_TAG                    // </tag> or </>
+	TOKEN_PLACEHOLDER                  // {0}, {name}
+	TOKEN_MALFORMED                    // "<" or "{" without a closing bracket
+)
+
+var TOKEN_KIND_NAMES = []string{
+	"text",
+	"line_break",
+	"tag",
+	"close_tag",
+	"placeholder",
+	"malformed",
+}
+
+func (k TokenKind) String() string {
+	if int(k) < 0 || int(k) >= len(TOKEN_KIND_NAMES) {
+		return "unknown"
+	}
+	return TOKEN_KIND_NAMES[k]
+}
+
+type Token struct {
+	Kind TokenKind
+	Raw  string // the original text of the token
+	Name string // tag or placeholder name
+}
+
+// Return true when the token should survive translation as-is
+func (t *Token) IsMarkup() bool {
+	return t.Kind == TOKEN_TAG || t.Kind == TOKEN_CLOSE_TAG || t.Kind == TOKEN_PLACEHOLDER
+}
+
+func parseTagName(body string) string {
+	body = strings.TrimSuffix(body, "/")
+	end := strings.IndexAny(body, " \t=/")
+	if end >= 0 {
+		body = body[:end]
+	}
+	return body
+}
+
+// Split text into literal runs, line breaks, tags and placeholders
+func Tokenize(text string) []Token {
+	tokens := []Token{}
+	literal := strings.Builder{}
+	flush := func() {
+		if literal.Len() > 0 {
+			tokens = append(tokens, Token{Kind: TOKEN_TEXT, Raw: literal.String()})
+			literal.Reset()
+		}
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		if c == '\r' && i+1 < len(text) && text[i+1] == '\n' {
+			flush()
+			tokens = append(tokens, Token{Kind: TOKEN_LINE_BREAK, Raw: "\r\n"})
+			i += 2
+			continue
+		} else if c == '\n' {
+			flush()
+			tokens = append(tokens, Token{Kind: TOKEN_LINE_BREAK, Raw: "\n"})
+			i++
+			continue
+		} else if c != '<' && c != '{' {
+			literal.WriteByte(c)
+			i++
+			continue
+		}
+
+		closer := byte('>')
+		if c == '{' {
+			closer = '}'
+		}
+		end := strings.IndexByte(text[i+1:], closer)
+		nested := strings.IndexByte(text[i+1:], c)
+		if end < 0 || (nested >= 0 && nested < end) {
+			flush()
+			tokens = append(tokens, Token{Kind: TOKEN_MALFORMED, Raw: string(c)})
+			i++
+			continue
+		}
+
+		flush()
+		raw := text[i : i+end+2]
+		body := raw[1 : len(raw)-1]
+		if c == '{' {
+			tokens = append(tokens, Token{Kind: TOKEN_PLACEHOLDER, Raw: raw, Name: body})
+		} else if strings.HasPrefix(body, "/") {
+			tokens = append(tokens, Token{Kind: TOKEN_CLOSE_TAG, Raw: raw, Name: strings.TrimSpace(body[1:])})
+		} else {
+			tokens = append(tokens, Token{Kind: TOKEN_TAG, Raw: raw, Name: parseTagName(body)})
+		}
+		i += end + 2
+	}
+	flush()
+	return tokens
+}
+
+// Count tags and placeholders in text.
+// Line breaks are not included because translations can change line counts.
+func GetTagMultiset(text string) map[string]int {
+	tags := map[string]int{}
+	for _, t := range Tokenize(text) {
+		if t.IsMarkup() {
+			tags[t.Raw]++
+		}
+	}
+	return tags
+}
+
+type MarkupIssue struct {
+	Id      string `json:"id"`
+	SubId   string `json:"sub_id,omitempty"`
+	Message string `json:"message"`
+}
+
+func (issue *MarkupIssue) String() string {
+	if issue.SubId == "" {
+		return fmt.Sprintf("%s: %s", issue.Id, issue.Message)
+	}
+	return fmt.Sprintf("%s (%s): %s", issue.Id, issue.SubId, issue.Message)
+}
+
+// Compare markup of a new text with the original one
+func CompareMarkup(orig string, text string) []string {
+	messages := []string{}
+	// Malformed markup in the original text (e.g. "1 < 2") is not an issue
+	origMalformed := map[string]int{}
+	for _, t := range Tokenize(orig) {
+		if t.Kind == TOKEN_MALFORMED {
+			origMalformed[t.Raw]++
+		}
+	}
+	for _, t := range Tokenize(text) {
+		if t.Kind != TOKEN_MALFORMED {
+			continue
+		}
+		if origMalformed[t.Raw] > 0 {
+			origMalformed[t.Raw]--
+			continue
+		}
+		messages = append(messages, fmt.Sprintf("malformed markup (%q)", t.Raw))
+	}
+
+	origTags := GetTagMultiset(orig)
+	newTags := GetTagMultiset(text)
+	keys := []string{}
+	for key := range origTags {
+		keys = append(keys, key)
+	}
+	for key := range newTags {
+		if _, ok := origTags[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		diff := newTags[key] - origTags[key]
+		if diff < 0 {
+			messages = append(messages, fmt.Sprintf("dropped %s (%d -> %d)", key, origTags[key], newTags[key]))
+		} else if diff > 0 {
+			messages = append(messages, fmt.Sprintf("unexpected %s (%d -> %d)", key, origTags[key], newTags[key]))
+		}
+	}
+	return messages
+}
+
+func (e *Entry) CheckMarkup(orig *Entry) []MarkupIssue {
+	issues := []MarkupIssue{}
+	for _, msg := range CompareMarkup(orig.Text, e.Text) {
+		issues = append(issues, MarkupIssue{Id: e.Id, Message: msg})
+	}
+	for i := range len(e.SubEntries) {
+		se := &e.SubEntries[i]
+		for j := range len(orig.SubEntries) {
+			if orig.SubEntries[j].Id != se.Id {
+				continue
+			}
+			for _, msg := range CompareMarkup(orig.SubEntries[j].Text, se.Text) {
+				issues = append(issues, MarkupIssue{Id: e.Id, SubId: se.Id, Message: msg})
+			}
+			break
+		}
+	}
+	return issues
+}
+
+// Check if tags and placeholders in the original data are preserved.
+func (uexp *Uexp) CheckMarkup(orig *Uexp) []MarkupIssue {
+	issues := []MarkupIssue{}
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		j := orig.FindEntry(e.Id, min(i, len(orig.Entries)-1))
+		if j < 0 {
+			continue
+		}
+		issues = append(issues, e.CheckMarkup(&orig.Entries[j])...)
+	}
+	return issues
+}
+
+type TagInfo struct {
+	Kind    string   `json:"kind"`
+	Name    string   `json:"name"`
+	Count   int      `json:"count"`
+	Samples []string `json:"samples"` // raw tokens
+	Entries []string `json:"entries"` // entry ids where the tag is found
+}
+
+// Collection of tag types found in assets
+type TagCatalog struct {
+	mutex sync.Mutex
+	tags  map[string]*TagInfo
+}
+
+const TAG_CATALOG_MAX_SAMPLES = 5
+
+func NewTagCatalog() *TagCatalog {
+	return &TagCatalog{tags: map[string]*TagInfo{}}
+}
+
+func (c *TagCatalog) addText(id string, text string) {
+	for _, t := range Tokenize(text) {
+		if t.Kind == TOKEN_TEXT || t.Kind == TOKEN_LINE_BREAK {
+			continue
+		}
+		key := t.Kind.String() + ":" + t.Name
+		info, ok := c.tags[key]
+		if !ok {
+			info = &TagInfo{Kind: t.Kind.String(), Name: t.Name}
+			c.tags[key] = info
+		}
+		info.Count++
+		if len(info.Samples) < TAG_CATALOG_MAX_SAMPLES && !slices.Contains(info.Samples, t.Raw) {
+			info.Samples = append(info.Samples, t.Raw)
+		}
+		if len(info.Entries) < TAG_CATALOG_MAX_SAMPLES && !slices.Contains(info.Entries, id) {
+			info.Entries = append(info.Entries, id)
+		}
+	}
+}
+
+func (c *TagCatalog) Add(uexp *Uexp) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		c.addText(e.Id, e.Text)
+		for j := range len(e.SubEntries) {
+			c.addText(e.Id, e.SubEntries[j].Text)
+		}
+	}
+}
+
+// Get tag types sorted by kind and name
+func (c *TagCatalog) GetTags() []*TagInfo {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	tags := make([]*TagInfo, 0, len(c.tags))
+	for _, info := range c.tags {
+		tags = append(tags, info)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Kind != tags[j].Kind {
+			return tags[i].Kind < tags[j].Kind
+		}
+		return tags[i].Name < tags[j].Name
+	})
+	return tags
+}
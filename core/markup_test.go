@@ -0,0 +1,93 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		text  string
+		kinds []TokenKind
+		names []string
+	}{
+		{"plain", []TokenKind{TOKEN_TEXT}, []string{""}},
+		{"a\r\nb\nc", []TokenKind{TOKEN_TEXT, TOKEN_LINE_BREAK, TOKEN_TEXT, TOKEN_LINE_BREAK, TOKEN_TEXT}, []string{"", "", "", "", ""}},
+		{"<Red>Cloud</>", []TokenKind{TOKEN_TAG, TOKEN_TEXT, TOKEN_CLOSE_TAG}, []string{"Red", "", ""}},
+		{"<img id=\"Btn\"/>", []TokenKind{TOKEN_TAG}, []string{"img"}},
+		{"</Red>", []TokenKind{TOKEN_CLOSE_TAG}, []string{"Red"}},
+		{"go to {0}.", []TokenKind{TOKEN_TEXT, TOKEN_PLACEHOLDER, TOKEN_TEXT}, []string{"", "0", ""}},
+		{"1 < 2", []TokenKind{TOKEN_TEXT, TOKEN_MALFORMED, TOKEN_TEXT}, []string{"", "", ""}},
+		{"{a{b}", []TokenKind{TOKEN_MALFORMED, TOKEN_TEXT, TOKEN_PLACEHOLDER}, []string{"", "", "b"}},
+		{"", []TokenKind{}, []string{}},
+	}
+	for _, test := range tests {
+		tokens := Tokenize(test.text)
+		kinds := []TokenKind{}
+		names := []string{}
+		raw := ""
+		for _, token := range tokens {
+			kinds = append(kinds, token.Kind)
+			names = append(names, token.Name)
+			raw += token.Raw
+		}
+		if !slices.Equal(kinds, test.kinds) || !slices.Equal(names, test.names) {
+			t.Errorf("Tokenize(%q): got %v %q, want %v %q", test.text, kinds, names, test.kinds, test.names)
+		}
+		if raw != test.text {
+			t.Errorf("Tokenize(%q): tokens do not restore the text (%q)", test.text, raw)
+		}
+	}
+}
+
+func TestCompareMarkup(t *testing.T) {
+	tests := []struct {
+		orig     string
+		text     string
+		messages []string
+	}{
+		{"<Red>Cloud</>", "<Red>クラウド</>", []string{}},
+		{"Hi\r\n{0}", "Hi {0}", []string{}}, // line breaks are not checked
+		{"<Red>Cloud</>", "Cloud</>", []string{"dropped <Red> (1 -> 0)"}},
+		{"{0}", "{0} {0}", []string{"unexpected {0} (1 -> 2)"}},
+		{"{0}", "{1}", []string{"dropped {0} (1 -> 0)", "unexpected {1} (0 -> 1)"}},
+		{"a", "a <b", []string{"malformed markup (\"<\")"}},
+		{"1 < 2", "1 < 2", []string{}},
+		{"1 < 2", "1 < 2 < 3", []string{"malformed markup (\"<\")"}},
+	}
+	for _, test := range tests {
+		messages := CompareMarkup(test.orig, test.text)
+		if !slices.Equal(messages, test.messages) {
+			t.Errorf("CompareMarkup(%q, %q): got %q, want %q", test.orig, test.text, messages, test.messages)
+		}
+	}
+}
+
+func TestCheckMarkup(t *testing.T) {
+	orig := &Entry{Id: "a", Text: "<Red>x</>", SubEntries: []SubEntry{{Id: "ACTOR", Text: "{0}"}}}
+	e := &Entry{Id: "a", Text: "<Red>y</>", SubEntries: []SubEntry{{Id: "ACTOR", Text: "z"}}}
+	issues := e.CheckMarkup(orig)
+	if len(issues) != 1 || issues[0].SubId != "ACTOR" || issues[0].String() != "a (ACTOR): dropped {0} (1 -> 0)" {
+		t.Errorf("CheckMarkup: got %v", issues)
+	}
+}
+
+func TestTagCatalog(t *testing.T) {
+	c := NewTagCatalog()
+	c.Add(&Uexp{Entries: []Entry{
+		{Id: "a", Text: "<Red>x</> {0}"},
+		{Id: "b", Text: "<Red>y</>", SubEntries: []SubEntry{{Id: "ACTOR", Text: "{0}"}}},
+	}})
+	tags := c.GetTags()
+	got := []string{}
+	for _, tag := range tags {
+		got = append(got, tag.Kind+":"+tag.Name)
+	}
+	want := []string{"close_tag:", "placeholder:0", "tag:Red"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("GetTags: got %q, want %q", got, want)
+	}
+	if tags[2].Count != 2 || !slices.Equal(tags[2].Entries, []string{"a", "b"}) {
+		t.Errorf("GetTags: unexpected info of <Red> (%+v)", tags[2])
+	}
+}
@@ -0,0 +1,265 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Language codes for translation APIs
+var MT_LANG_CODES = map[string]string{
+	"BR": "pt-BR",
+	"CN": "zh-Hans",
+	"DE": "de",
+	"ES": "es",
+	"FR": "fr",
+	"IT": "it",
+	"JP": "ja",
+	"KR": "ko",
+	"MX": "es",
+	"TW": "zh-Hant",
+	"US": "en",
+}
+
+var MT_API_LIST = []string{
+	"libre",
+	"deepl",
+}
+
+// Backend for machine translation.
+// It should return translated texts in the same order as the input.
+type Translator interface {
+	Translate(texts []string, sourceLang string, targetLang string) ([]string, error)
+}
+
+func postJson(client *http.Client, url string, header map[string]string, body interface{}, response interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	resData, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("translation request failed (%s): %s", res.Status, string(resData))
+	}
+	return json.Unmarshal(resData, response)
+}
+
+// Backend for LibreTranslate compatible APIs (POST /translate)
+type LibreTranslator struct {
+	Url    string
+	ApiKey string
+	Client *http.Client
+}
+
+func NewLibreTranslator(url string, apiKey string) *LibreTranslator {
+	return &LibreTranslator{Url: url, ApiKey: apiKey, Client: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+func libreLangCode(lang string) string {
+	if lang == "zh-Hant" {
+		return "zt"
+	}
+	return strings.ToLower(strings.SplitN(lang, "-", 2)[0])
+}
+
+func (t *LibreTranslator) Translate(texts []string, sourceLang string, targetLang string) ([]string, error) {
+	body := map[string]interface{}{
+		"q":      texts,
+		"source": libreLangCode(sourceLang),
+		"target": libreLangCode(targetLang),
+		"format": "html",
+	}
+	if t.ApiKey != "" {
+		body["api_key"] = t.ApiKey
+	}
+	response := struct {
+		TranslatedText []string `json:"translatedText"`
+	}{}
+	if err := postJson(t.Client, t.Url, nil, body, &response); err != nil {
+		return nil, err
+	}
+	return response.TranslatedText, nil
+}
+
+// Backend for DeepL compatible APIs (POST /v2/translate)
+type DeepLTranslator struct {
+	Url    string
+	ApiKey string
+	Client *http.Client
+}
+
+func NewDeepLTranslator(url string, apiKey string) *DeepLTranslator {
+	return &DeepLTranslator{Url: url, ApiKey: apiKey, Client: &http.Client{Timeout: 5 * time.Minute}}
+}
+
+func (t *DeepLTranslator) Translate(texts []string, sourceLang string, targetLang string) ([]string, error) {
+	body := map[string]interface{}{
+		"text":         texts,
+		"source_lang":  strings.ToUpper(strings.SplitN(sourceLang, "-", 2)[0]),
+		"target_lang":  strings.ToUpper(targetLang),
+		"tag_handling": "xml",
+	}
+	header := map[string]string{}
+	if t.ApiKey != "" {
+		header["Authorization"] = "DeepL-Auth-Key " + t.ApiKey
+	}
+	response := struct {
+		Translations []struct {
+			Text string `json:"text"`
+		} `json:"translations"`
+	}{}
+	if err := postJson(t.Client, t.Url, header, body, &response); err != nil {
+		return nil, err
+	}
+	translated := make([]string, 0, len(response.Translations))
+	for _, tr := range response.Translations {
+		translated = append(translated, tr.Text)
+	}
+	return translated, nil
+}
+
+func NewTranslator(api string, url string, apiKey string) Translator {
+	if api == "libre" {
+		return NewLibreTranslator(url, apiKey)
+	} else if api == "deepl" {
+		return NewDeepLTranslator(url, apiKey)
+	}
+	Throw(fmt.Errorf("unknown translation api detected. (%s)", api))
+	return nil
+}
+
+var PROTECTED_TAG_REGEX = regexp.MustCompile(`<x\s+id\s*=\s*"(\d+)"\s*/>`)
+
+// Replace tags, placeholders and line breaks with <x id="N"/>
+// so that translation APIs keep them as-is.
+// Literal runs are escaped as html.
+func ProtectMarkup(text string) (string, []string) {
+	protected := strings.Builder{}
+	markup := []string{}
+	for _, t := range Tokenize(text) {
+		if t.Kind == TOKEN_TEXT || t.Kind == TOKEN_MALFORMED {
+			protected.WriteString(html.EscapeString(t.Raw))
+			continue
+		}
+		fmt.Fprintf(&protected, `<x id="%d"/>`, len(markup))
+		markup = append(markup, t.Raw)
+	}
+	return protected.String(), markup
+}
+
+// Restore text converted by ProtectMarkup
+func RestoreMarkup(text string, markup []string) (string, error) {
+	used := make([]bool, len(markup))
+	var restoreErr error
+	restored := PROTECTED_TAG_REGEX.ReplaceAllStringFunc(text, func(tag string) string {
+		id, _ := strconv.Atoi(PROTECTED_TAG_REGEX.FindStringSubmatch(tag)[1])
+		if id >= len(markup) {
+			restoreErr = fmt.Errorf("unknown protected tag detected (%s)", tag)
+			return tag
+		}
+		if used[id] {
+			restoreErr = fmt.Errorf("translation duplicated markup (%q)", markup[id])
+			return tag
+		}
+		used[id] = true
+		return "\x00" + strconv.Itoa(id) + "\x00"
+	})
+	if restoreErr != nil {
+		return "", restoreErr
+	}
+	for i := range len(used) {
+		if !used[i] {
+			return "", fmt.Errorf("translation dropped markup (%q)", markup[i])
+		}
+	}
+
+	// Unescape literal runs, then put the markup back
+	restored = html.UnescapeString(restored)
+	for i := range len(markup) {
+		restored = strings.Replace(restored, "\x00"+strconv.Itoa(i)+"\x00", markup[i], 1)
+	}
+	return restored, nil
+}
+
+func needsTranslation(e *Entry, orig *Entry) bool {
+	if e.Text == "" || e.Text == e.Id || e.Text != orig.Text {
+		return false
+	}
+	for _, r := range e.Text {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
+
+// Translate entries in dst that still have the same text as src.
+// Translated entries are marked as machine-translated.
+// It returns the number of translated entries.
+func MachineTranslate(src *Uexp, dst *Uexp, tr Translator, sourceLang string, targetLang string, batchSize int) int {
+	type job struct {
+		entry  *Entry
+		text   string
+		markup []string
+	}
+	jobs := []job{}
+	for i := range len(dst.Entries) {
+		e := &dst.Entries[i]
+		j := src.FindEntry(e.Id, min(i, len(src.Entries)-1))
+		if j < 0 || !needsTranslation(e, &src.Entries[j]) {
+			continue
+		}
+		text, markup := ProtectMarkup(src.Entries[j].Text)
+		jobs = append(jobs, job{entry: e, text: text, markup: markup})
+	}
+
+	count := 0
+	for start := 0; start < len(jobs); start += batchSize {
+		batch := jobs[start:min(start+batchSize, len(jobs))]
+		texts := make([]string, 0, len(batch))
+		for _, j := range batch {
+			texts = append(texts, j.text)
+		}
+		translated, err := tr.Translate(texts, sourceLang, targetLang)
+		if err != nil {
+			Throw(err)
+		}
+		if len(translated) != len(batch) {
+			Throw(fmt.Errorf("unexpected translation count: %d (expected %d)", len(translated), len(batch)))
+		}
+		for i, j := range batch {
+			text, err := RestoreMarkup(translated[i], j.markup)
+			if err != nil {
+				Throw(fmt.Errorf("%s: %v", j.entry.Id, err))
+			}
+			j.entry.Text = text
+			j.entry.MachineTranslated = true
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProtectMarkup(t *testing.T) {
+	tests := []struct {
+		text      string
+		protected string
+		markup    []string
+	}{
+		{"plain", "plain", []string{}},
+		{"<Red>Cloud</> & {0}", `<x id="0"/>Cloud<x id="1"/> &amp; <x id="2"/>`, []string{"<Red>", "</>", "{0}"}},
+		{"a\r\nb", `a<x id="0"/>b`, []string{"\r\n"}},
+		{"1 < 2", "1 &lt; 2", []string{}},
+	}
+	for _, test := range tests {
+		protected, markup := ProtectMarkup(test.text)
+		if protected != test.protected || !slices.Equal(markup, test.markup) {
+			t.Errorf("ProtectMarkup(%q): got %q %q, want %q %q", test.text, protected, markup, test.protected, test.markup)
+		}
+	}
+}
+
+func TestRestoreMarkup(t *testing.T) {
+	markup := []string{"<Red>", "</>", "{0}"}
+	tests := []struct {
+		text     string
+		restored string
+		hasError bool
+	}{
+		{`<x id="0"/>クラウド<x id="1"/> &amp; <x id="2"/>`, "<Red>クラウド</> & {0}", false},
+		{`<x id = "2" /> <x id="0"/>a<x id="1"/>`, "{0} <Red>a</>", false}, // reordered
+		{`<x id="0"/>a<x id="1"/>`, "", true},                              // dropped
+		{`<x id="0"/>a<x id="1"/><x id="2"/><x id="2"/>`, "", true},        // duplicated
+		{`<x id="0"/>a<x id="1"/><x id="2"/><x id="3"/>`, "", true},        // unknown
+	}
+	for _, test := range tests {
+		restored, err := RestoreMarkup(test.text, markup)
+		if (err != nil) != test.hasError {
+			t.Errorf("RestoreMarkup(%q): unexpected error state (%v)", test.text, err)
+		} else if restored != test.restored {
+			t.Errorf("RestoreMarkup(%q): got %q, want %q", test.text, restored, test.restored)
+		}
+	}
+}
+
+// Fake LibreTranslate server that upper-cases words
+func newLibreServer(t *testing.T, requests *[]map[string]interface{}) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		*requests = append(*requests, body)
+		replacer := strings.NewReplacer("hi", "HI", "bye", "BYE")
+		translated := []string{}
+		for _, q := range body["q"].([]interface{}) {
+			translated = append(translated, replacer.Replace(q.(string)))
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"translatedText": translated})
+	}))
+}
+
+func TestMachineTranslate(t *testing.T) {
+	requests := []map[string]interface{}{}
+	server := newLibreServer(t, &requests)
+	defer server.Close()
+
+	src := &Uexp{Entries: []Entry{
+		{Id: "a", Text: "<Red>hi</> {0}"},
+		{Id: "b", Text: "done"},
+		{Id: "c", Text: "bye"},
+	}}
+	dst := &Uexp{Entries: []Entry{
+		{Id: "a", Text: "<Red>hi</> {0}"},
+		{Id: "b", Text: "済み"}, // translated
+		{Id: "c", Text: "bye"},
+	}}
+	tr := NewTranslator("libre", server.URL, "key")
+	count := MachineTranslate(src, dst, tr, "en", "zh-Hant", 1)
+	if count != 2 || dst.Entries[0].Text != "<Red>HI</> {0}" || dst.Entries[1].Text != "済み" || dst.Entries[2].Text != "BYE" {
+		t.Errorf("MachineTranslate: got %d, %+v", count, dst.Entries)
+	}
+	if !dst.Entries[0].MachineTranslated || dst.Entries[1].MachineTranslated {
+		t.Errorf("MachineTranslate: unexpected flags (%+v)", dst.Entries)
+	}
+	if len(requests) != 2 || requests[0]["source"] != "en" || requests[0]["target"] != "zt" || requests[0]["api_key"] != "key" {
+		t.Errorf("MachineTranslate: unexpected requests (%v)", requests)
+	}
+}
+
+func TestDeepLTranslator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "DeepL-Auth-Key key" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"translations": []map[string]string{{"text": "Hallo"}},
+		})
+	}))
+	defer server.Close()
+
+	translated, err := NewTranslator("deepl", server.URL, "key").Translate([]string{"Hello"}, "en", "de")
+	if err != nil || !slices.Equal(translated, []string{"Hallo"}) {
+		t.Errorf("Translate: got %q, %v", translated, err)
+	}
+	_, err = NewTranslator("deepl", server.URL, "wrong").Translate([]string{"Hello"}, "en", "de")
+	if err == nil {
+		t.Errorf("Translate: errors of the api should be returned")
+	}
+}
@@ -0,0 +1,377 @@
+package core
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// Reader for little-endian binary in memory
+type byteReader struct {
+	data   []byte
+	offset int
+	err    error
+}
+
+func (r *byteReader) read(size int) []byte {
+	if r.err != nil || size < 0 || r.offset+size > len(r.data) {
+		if r.err == nil {
+			r.err = fmt.Errorf("unexpected end of data at %d", r.offset)
+		}
+		return make([]byte, size)
+	}
+	buf := r.data[r.offset : r.offset+size]
+	r.offset += size
+	return buf
+}
+
+func (r *byteReader) uint8() uint8 {
+	return r.read(1)[0]
+}
+
+func (r *byteReader) uint16() uint16 {
+	return binary.LittleEndian.Uint16(r.read(2))
+}
+
+func (r *byteReader) int32() int32 {
+	return int32(binary.LittleEndian.Uint32(r.read(4)))
+}
+
+func (r *byteReader) uint32() uint32 {
+	return binary.LittleEndian.Uint32(r.read(4))
+}
+
+func (r *byteReader) int64() int64 {
+	return int64(binary.LittleEndian.Uint64(r.read(8)))
+}
+
+func (r *byteReader) uint64() uint64 {
+	return binary.LittleEndian.Uint64(r.read(8))
+}
+
+func (r *byteReader) float32() float32 {
+	return math.Float32frombits(r.uint32())
+}
+
+func (r *byteReader) float64() float64 {
+	return math.Float64frombits(r.uint64())
+}
+
+// Read FString. Negative length means UTF-16.
+func (r *byteReader) fstring() string {
+	length := int(r.int32())
+	if length == 0 || r.err != nil {
+		return ""
+	}
+	if length > 0 {
+		return strings.TrimSuffix(string(r.read(length)), "\x00")
+	}
+	buf := r.read(-length * 2)
+	units := make([]uint16, 0, -length)
+	for i := 0; i+1 < len(buf); i += 2 {
+		units = append(units, binary.LittleEndian.Uint16(buf[i:]))
+	}
+	return strings.TrimSuffix(string(utf16.Decode(units)), "\x00")
+}
+
+// Export of a package. Offset is relative to the export data.
+type PackageExport struct {
+	Name       string
+	Class      string // empty when the class is not resolved
+	Offset     int
+	Size       int
+	classIndex uint64 // FPackageObjectIndex of the class (FF7R2)
+}
+
+// Package with export data that can be read or patched in place
+type PackageAsset struct {
+	uasset  Uasset
+	Data    []byte // .uexp for FF7R, .uasset for FF7R2
+	Exports []PackageExport
+	reader  *PropertyReader // reader for unversioned properties (see SetMappings)
+}
+
+func (pkg *PackageAsset) getName(index uint32, number uint32) (string, error) {
+	if index >= uint32(len(pkg.uasset.Names)) {
+		return "", fmt.Errorf("unexpected name index: %d", index)
+	}
+	name := pkg.uasset.Names[index]
+	if number > 0 {
+		name += "_" + strconv.Itoa(int(number-1))
+	}
+	return name, nil
+}
+
+func (pkg *PackageAsset) readName(r *byteReader) string {
+	index, number := r.uint32(), r.uint32()
+	if r.err != nil {
+		return ""
+	}
+	name, err := pkg.getName(index, number)
+	if err != nil {
+		r.err = err
+	}
+	return name
+}
+
+// UE4 object versions that change the summary and the maps of legacy packages
+const (
+	VER_UE4_SERIALIZE_TEXT_IN_PACKAGES             = 459
+	VER_UE4_PRELOAD_DEPENDENCIES_IN_COOKED_EXPORTS = 507
+	VER_UE4_TEMPLATE_INDEX_IN_COOKED_EXPORTS       = 508
+	VER_UE4_64BIT_EXPORTMAP_SERIALSIZES            = 511
+	VER_UE4_NON_OUTER_PACKAGE_IMPORT               = 520 // adds PackageName to imports
+)
+
+// Read the export map of a legacy package (FF7R)
+func (pkg *PackageAsset) readLegacyExports() {
+	r := &byteReader{data: pkg.uasset.rawBin}
+	r.offset = 4
+	legacyVersion := r.int32()
+	if legacyVersion > -4 || legacyVersion < -7 {
+		Throw(fmt.Errorf("unsupported legacy file version. (%d)", legacyVersion))
+	}
+	if legacyVersion != -4 {
+		r.int32() // LegacyUE3Version
+	}
+	fileVersion := r.int32()
+	if fileVersion < VER_UE4_SERIALIZE_TEXT_IN_PACKAGES || fileVersion >= VER_UE4_NON_OUTER_PACKAGE_IMPORT {
+		Throw(fmt.Errorf("unsupported package version. (%d)", fileVersion))
+	}
+	r.int32() // FileVersionLicenseeUE4
+	if r.int32() != 0 {
+		Throw("unsupported package summary (custom versions should be empty)")
+	}
+	r.int32()   // TotalHeaderSize
+	r.fstring() // FolderName
+	r.uint32()  // PackageFlags
+	r.read(8)   // NameCount and NameOffset
+	r.read(8)   // GatherableTextDataCount and GatherableTextDataOffset
+	exportCount, exportOffset := r.int32(), r.int32()
+	importCount, importOffset := r.int32(), r.int32()
+	if r.err != nil {
+		Throw(fmt.Errorf("failed to read package summary: %v", r.err))
+	}
+
+	// Object names of imports. FObjectImport has ClassPackage, ClassName, OuterIndex and ObjectName.
+	imports := make([]string, 0, max(importCount, 0))
+	for i := range importCount {
+		r.offset = int(importOffset) + int(i)*28 + 20
+		imports = append(imports, pkg.readName(r))
+	}
+
+	r.offset = int(exportOffset)
+	headerSize := len(pkg.uasset.rawBin)
+	classIndices := []int32{}
+	for range exportCount {
+		classIndices = append(classIndices, r.int32())
+		r.read(4) // SuperIndex
+		if fileVersion >= VER_UE4_TEMPLATE_INDEX_IN_COOKED_EXPORTS {
+			r.read(4) // TemplateIndex
+		}
+		r.read(4) // OuterIndex
+		name := pkg.readName(r)
+		r.read(4) // ObjectFlags
+		var size, offset int64
+		if fileVersion >= VER_UE4_64BIT_EXPORTMAP_SERIALSIZES {
+			size, offset = r.int64(), r.int64()
+		} else {
+			size, offset = int64(r.int32()), int64(r.int32())
+		}
+		r.read(12 + 16 + 4 + 8) // bForcedExport, bNotForClient, bNotForServer, PackageGuid, PackageFlags, bNotAlwaysLoadedForEditorGame, bIsAsset
+		if fileVersion >= VER_UE4_PRELOAD_DEPENDENCIES_IN_COOKED_EXPORTS {
+			r.read(20) // FirstExportDependency and dependency counts
+		}
+		pkg.Exports = append(pkg.Exports, PackageExport{Name: name, Offset: int(offset) - headerSize, Size: int(size)})
+	}
+	if r.err != nil {
+		Throw(fmt.Errorf("failed to read export map: %v", r.err))
+	}
+
+	// FPackageIndex is negative for imports and positive for exports
+	for i, index := range classIndices {
+		if index < 0 && int(-index-1) < len(imports) {
+			pkg.Exports[i].Class = imports[-index-1]
+		} else if index > 0 && int(index-1) < len(pkg.Exports) {
+			pkg.Exports[i].Class = pkg.Exports[index-1].Name
+		}
+	}
+}
+
+// Size of FExportMapEntry in zen packages
+const ZEN_EXPORT_ENTRY_SIZE = 72
+
+// Read the export map of a zen package (FF7R2)
+func (pkg *PackageAsset) readZenExports() {
+	summary := pkg.uasset.Summary
+	r := &byteReader{data: pkg.Data}
+	count := int(summary.ExportBundleEntriesOffset-summary.ExportOffset) / ZEN_EXPORT_ENTRY_SIZE
+	for i := range count {
+		r.offset = int(summary.ExportOffset) + i*ZEN_EXPORT_ENTRY_SIZE
+		offset, size := r.int64(), r.int64()
+		// The upper bits of FMappedName are used for the name type
+		index, number := r.uint32()&0x3FFFFFFF, r.uint32()
+		name, err := pkg.getName(index, number)
+		if err != nil && r.err == nil {
+			r.err = err
+		}
+		r.read(8) // OuterIndex
+		pkg.Exports = append(pkg.Exports, PackageExport{
+			Name:       name,
+			Offset:     summary.GetUassetEndOffset() + int(offset) - int(summary.CookedHeaderSize),
+			Size:       int(size),
+			classIndex: r.uint64(),
+		})
+	}
+	if r.err != nil {
+		Throw(fmt.Errorf("failed to read export map: %v", r.err))
+	}
+	for i := range len(pkg.Exports) {
+		index := pkg.Exports[i].classIndex
+		if index>>62 == ZEN_INDEX_EXPORT && int(index&ZEN_INDEX_MASK) < len(pkg.Exports) {
+			pkg.Exports[i].Class = pkg.Exports[index&ZEN_INDEX_MASK].Name
+		}
+	}
+}
+
+// Types of FPackageObjectIndex (upper 2 bits)
+const (
+	ZEN_INDEX_EXPORT = iota
+	ZEN_INDEX_SCRIPT_IMPORT
+	ZEN_INDEX_PACKAGE_IMPORT
+	ZEN_INDEX_NULL
+)
+
+const ZEN_INDEX_MASK = 1<<62 - 1
+
+// Script modules of classes.
+// Script imports of zen packages are hashes of "/Script/<module>.<class>", so they are resolved with class names in mappings.
+var SCRIPT_MODULES = []string{
+	"CoreUObject",
+	"Engine",
+	"SlateCore",
+	"Slate",
+	"UMG",
+	"EndGame",
+}
+
+// Get FPackageObjectIndex of a script object (e.g. "/Script/UMG.TextBlock")
+func scriptObjectIndex(path string) uint64 {
+	buf := []byte{}
+	for _, c := range utf16.Encode([]rune(strings.ToLower(path))) {
+		if c == '.' || c == ':' {
+			c = '/'
+		}
+		buf = binary.LittleEndian.AppendUint16(buf, c)
+	}
+	return ZEN_INDEX_SCRIPT_IMPORT<<62 | CityHash64(buf)&ZEN_INDEX_MASK
+}
+
+// Resolve classes of script imports (FF7R2) with class names in mappings
+func (pkg *PackageAsset) resolveScriptClasses(m *Mappings) {
+	classes := map[uint64]string{}
+	for name := range m.Schemas {
+		for _, module := range SCRIPT_MODULES {
+			classes[scriptObjectIndex("/Script/"+module+"."+name)] = name
+		}
+	}
+	for i := range len(pkg.Exports) {
+		export := &pkg.Exports[i]
+		if class, ok := classes[export.classIndex]; ok && export.Class == "" {
+			export.Class = class
+		}
+	}
+}
+
+// Check if export data has unversioned properties (FF7R2)
+func (pkg *PackageAsset) HasUnversionedProperties() bool {
+	return pkg.uasset.Ver != VER_FF7R && pkg.uasset.Summary.PkgFlags&PKG_UNVERSIONED_PROPERTIES != 0
+}
+
+// Set mappings to read and patch unversioned properties.
+// It also resolves classes of exports with the mappings.
+func (pkg *PackageAsset) SetMappings(m *Mappings) error {
+	reader, err := NewPropertyReader(pkg, m)
+	if err != nil {
+		return err
+	}
+	pkg.reader = reader
+	pkg.resolveScriptClasses(m)
+	return nil
+}
+
+// Override classes of exports. The key is an export name, or empty for all exports.
+func (pkg *PackageAsset) SetExportClasses(classes map[string]string) {
+	for i := range len(pkg.Exports) {
+		export := &pkg.Exports[i]
+		if class, ok := classes[export.Name]; ok {
+			export.Class = class
+		} else if class, ok := classes[""]; ok {
+			export.Class = class
+		}
+	}
+}
+
+func LoadPackageAsset(filePath string) *PackageAsset {
+	pkg := &PackageAsset{}
+	s := NewSerializer()
+	Printf("Reading %s...\n", filePath)
+	file := OpenFile(filePath)
+	defer file.Close()
+	s.SetReadFile(file)
+	pkg.uasset.Read(s)
+
+	dataPath := filePath
+	if pkg.uasset.Ver == VER_FF7R {
+		dataPath = RemoveExtension(filePath) + ".uexp"
+		Printf("Reading %s...\n", dataPath)
+	}
+	data, err := os.ReadFile(dataPath)
+	if err != nil {
+		Throw(err)
+	}
+	pkg.Data = data
+
+	if pkg.uasset.Ver == VER_FF7R {
+		pkg.readLegacyExports()
+	} else {
+		pkg.readZenExports()
+	}
+	for _, export := range pkg.Exports {
+		if export.Offset < 0 || export.Size < 0 || export.Offset+export.Size > len(pkg.Data) {
+			Throw(fmt.Errorf("unexpected export detected. (%s, offset: %d, size: %d)", export.Name, export.Offset, export.Size))
+		}
+	}
+	return pkg
+}
+
+func (pkg *PackageAsset) FindExport(name string) *PackageExport {
+	for i := range len(pkg.Exports) {
+		if pkg.Exports[i].Name == name {
+			return &pkg.Exports[i]
+		}
+	}
+	return nil
+}
+
+func (pkg *PackageAsset) WriteToFile(outPath string) {
+	s := NewSerializer()
+	if pkg.uasset.Ver == VER_FF7R {
+		// .uasset has no changes
+		Printf("Writing %s...\n", outPath)
+		file := CreateFile(outPath)
+		defer file.Close()
+		s.SetWriteFile(file)
+		s.Write(pkg.uasset.rawBin)
+		outPath = RemoveExtension(outPath) + ".uexp"
+	}
+	Printf("Writing %s...\n", outPath)
+	file := CreateFile(outPath)
+	defer file.Close()
+	s.SetWriteFile(file)
+	s.Write(pkg.Data)
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// Make a legacy package summary with an import (CanvasPanelSlot) and two exports.
+func makeTestLegacyHeader(fileVersion int32, customVersions int32) []byte {
+	le := binary.LittleEndian
+	data := le.AppendUint32(nil, 0x9E2A83C1)             // Tag
+	data = le.AppendUint32(data, uint32(0xFFFFFFF9))     // LegacyFileVersion (-7)
+	data = le.AppendUint32(data, 864)                    // LegacyUE3Version
+	data = le.AppendUint32(data, uint32(fileVersion))    // FileVersionUE4
+	data = le.AppendUint32(data, 0)                      // FileVersionLicenseeUE4
+	data = le.AppendUint32(data, uint32(customVersions)) // number of custom versions
+	data = le.AppendUint32(data, 0)                      // TotalHeaderSize
+	data = le.AppendUint32(data, 5)                      // FolderName
+	data = append(data, "None\x00"...)
+	data = le.AppendUint32(data, 0)          // PackageFlags
+	data = append(data, make([]byte, 16)...) // names and gatherable text data
+	summaryEnd := len(data) + 16
+	importSize, exportSize := 28, 4*4+8+4+8+8+40+20
+	data = le.AppendUint32(data, 2)                             // ExportCount
+	data = le.AppendUint32(data, uint32(summaryEnd+importSize)) // ExportOffset
+	data = le.AppendUint32(data, 1)                             // ImportCount
+	data = le.AppendUint32(data, uint32(summaryEnd))            // ImportOffset
+
+	// FObjectImport (CanvasPanelSlot)
+	data = append(data, make([]byte, 20)...)
+	data = le.AppendUint32(data, 1)
+	data = le.AppendUint32(data, 0)
+
+	headerSize := len(data) + exportSize*2
+	for i, classIndex := range []int32{-1, 1} {
+		data = le.AppendUint32(data, uint32(classIndex))
+		data = append(data, make([]byte, 12)...) // SuperIndex, TemplateIndex and OuterIndex
+		data = le.AppendUint32(data, 1)          // CanvasPanelSlot_<i>
+		data = le.AppendUint32(data, uint32(i+1))
+		data = le.AppendUint32(data, 0)                       // ObjectFlags
+		data = le.AppendUint64(data, 10)                      // SerialSize
+		data = le.AppendUint64(data, uint64(headerSize+i*10)) // SerialOffset
+		data = append(data, make([]byte, 40+20)...)
+	}
+	return data
+}
+
+func TestReadLegacyExports(t *testing.T) {
+	pkg := &PackageAsset{}
+	pkg.uasset.Names = []string{"None", "CanvasPanelSlot"}
+	pkg.uasset.rawBin = makeTestLegacyHeader(516, 0)
+	pkg.readLegacyExports()
+	want := []PackageExport{
+		{Name: "CanvasPanelSlot_0", Class: "CanvasPanelSlot", Offset: 0, Size: 10},
+		{Name: "CanvasPanelSlot_1", Class: "CanvasPanelSlot_0", Offset: 10, Size: 10},
+	}
+	if len(pkg.Exports) != len(want) {
+		t.Fatalf("readLegacyExports: got %+v", pkg.Exports)
+	}
+	for i := range want {
+		if pkg.Exports[i] != want[i] {
+			t.Errorf("readLegacyExports: got %+v, want %+v", pkg.Exports[i], want[i])
+		}
+	}
+
+	// Summaries that have different layouts are not supported
+	for _, header := range [][]byte{
+		makeTestLegacyHeader(520, 0),
+		makeTestLegacyHeader(400, 0),
+		makeTestLegacyHeader(516, 1),
+	} {
+		pkg := &PackageAsset{}
+		pkg.uasset.Names = []string{"None", "CanvasPanelSlot"}
+		pkg.uasset.rawBin = header
+		if err := Try(pkg.readLegacyExports); err == nil {
+			t.Errorf("readLegacyExports should throw an error")
+		}
+	}
+}
+
+func TestReadZenExports(t *testing.T) {
+	le := binary.LittleEndian
+	data := make([]byte, 16)
+	textBlock := scriptObjectIndex("/Script/UMG.TextBlock")
+	for i, classIndex := range []uint64{textBlock, ZEN_INDEX_EXPORT<<62 | 0} {
+		data = le.AppendUint64(data, uint64(100+i*10)) // CookedSerialOffset
+		data = le.AppendUint64(data, 10)               // CookedSerialSize
+		data = le.AppendUint32(data, 1)                // TextBlock_<i>
+		data = le.AppendUint32(data, uint32(i+1))
+		data = le.AppendUint64(data, 0) // OuterIndex
+		data = le.AppendUint64(data, classIndex)
+		data = append(data, make([]byte, ZEN_EXPORT_ENTRY_SIZE-40)...)
+	}
+	exportEnd := len(data)
+	data = append(data, make([]byte, 20)...)
+
+	pkg := &PackageAsset{Data: data}
+	pkg.uasset.Ver = VER_FF7R2
+	pkg.uasset.Names = []string{"None", "TextBlock"}
+	pkg.uasset.Summary = &ZenPackageSummary{
+		CookedHeaderSize:          100,
+		ExportOffset:              16,
+		ExportBundleEntriesOffset: int32(exportEnd),
+		GraphDataOffset:           int32(exportEnd),
+		GraphDataSize:             0,
+	}
+	pkg.readZenExports()
+	if len(pkg.Exports) != 2 {
+		t.Fatalf("readZenExports: got %+v", pkg.Exports)
+	}
+	export0, export1 := pkg.Exports[0], pkg.Exports[1]
+	if export0.Name != "TextBlock_0" || export0.Offset != exportEnd || export0.Size != 10 || export0.Class != "" {
+		t.Errorf("readZenExports: got %+v", export0)
+	}
+	if export1.Name != "TextBlock_1" || export1.Offset != exportEnd+10 || export1.Class != "TextBlock_0" {
+		t.Errorf("readZenExports: got %+v", export1)
+	}
+
+	// Script imports are resolved with mappings
+	pkg.resolveScriptClasses(&Mappings{Schemas: map[string]*Schema{"TextBlock": {}}})
+	if pkg.Exports[0].Class != "TextBlock" {
+		t.Errorf("resolveScriptClasses: got %q", pkg.Exports[0].Class)
+	}
+}
@@ -92,7 +92,7 @@ func CreateFile(path string) *os.File {
 }
 
 func FilesAreEqual(file1Path, file2Path string) (bool, error) {
-	fmt.Printf("Comparing %s and %s...\n", file1Path, file2Path)
+	Printf("Comparing %s and %s...\n", file1Path, file2Path)
 	// Open the first file
 	file1, err := os.Open(file1Path)
 	if err != nil {
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// An event of --progress json. Events are written as JSON lines.
+//
+//	start:        mode, version, inputs, workers, total (number of input files)
+//	file-started: path
+//	file-done:    path, processed, done, duration_ms
+//	warning:      message
+//	error:        message, path, trace
+//	summary:      processed, failed, duration_ms (also emitted before exiting with a fatal error)
+type ProgressEvent struct {
+	Event      string   `json:"event"`
+	Mode       string   `json:"mode,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	Inputs     []string `json:"inputs,omitempty"`
+	Workers    int      `json:"workers,omitempty"`
+	Total      *int     `json:"total,omitempty"`
+	Path       string   `json:"path,omitempty"`
+	Processed  *int     `json:"processed,omitempty"`
+	Done       int      `json:"done,omitempty"` // number of finished files
+	Failed     *int     `json:"failed,omitempty"`
+	DurationMs *int64   `json:"duration_ms,omitempty"`
+	Message    string   `json:"message,omitempty"`
+	Trace      string   `json:"trace,omitempty"`
+}
+
+var progressMutex sync.Mutex
+var progressWriter io.Writer // nil means events are disabled
+var progressDone int
+
+// Counts for the summary of a fatal error
+var progressStart time.Time
+var progressProcessed int
+var progressFailed int
+
+// Write progress events to w. Other messages should be written to another stream.
+func EnableProgress(w io.Writer) {
+	progressMutex.Lock()
+	defer progressMutex.Unlock()
+	progressWriter = w
+	progressStart = time.Now()
+}
+
+func ProgressEnabled() bool {
+	progressMutex.Lock()
+	defer progressMutex.Unlock()
+	return progressWriter != nil
+}
+
+// Write an event as a line. Events from workers never interleave.
+func EmitProgress(event ProgressEvent) {
+	progressMutex.Lock()
+	defer progressMutex.Unlock()
+	if progressWriter == nil {
+		return
+	}
+	if event.Event == "file-done" {
+		progressDone++
+		event.Done = progressDone
+		if event.Processed != nil {
+			progressProcessed += *event.Processed
+		}
+	} else if event.Event == "error" && event.Path != "" {
+		progressFailed++
+	}
+	// Encode writes a line without indents
+	encoder := json.NewEncoder(progressWriter)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(event)
+}
+
+func EmitFileDone(path string, processed int, start time.Time) {
+	duration := time.Since(start).Milliseconds()
+	EmitProgress(ProgressEvent{Event: "file-done", Path: path, Processed: &processed, DurationMs: &duration})
+}
+
+func EmitSummary(processed int, failed int, start time.Time) {
+	duration := time.Since(start).Milliseconds()
+	EmitProgress(ProgressEvent{Event: "summary", Processed: &processed, Failed: &failed, DurationMs: &duration})
+}
+
+// Emit a summary with files that are done before a fatal error
+func emitFatalSummary() {
+	progressMutex.Lock()
+	processed, failed, start := progressProcessed, progressFailed, progressStart
+	progressMutex.Unlock()
+	EmitSummary(processed, failed, start)
+}
+
+// Writer for messages and text reports. It should be set before workers start.
+var outputWriter io.Writer = os.Stdout
+
+// Write messages to w instead of stdout (e.g. stderr to keep stdout for progress events)
+func SetOutput(w io.Writer) {
+	outputWriter = w
+}
+
+func Output() io.Writer {
+	return outputWriter
+}
+
+func Printf(format string, a ...interface{}) {
+	fmt.Fprintf(outputWriter, format, a...)
+}
+
+func Println(a ...interface{}) {
+	fmt.Fprintln(outputWriter, a...)
+}
+
+// Print a warning, or emit it as an event with --progress json
+func Warn(format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if ProgressEnabled() {
+		EmitProgress(ProgressEvent{Event: "warning", Message: msg})
+		return
+	}
+	Printf("Warning: %s\n", msg)
+}
+
+// Print an error that does not stop the tool, or emit it as an event with --progress json
+func PrintError(err error, path string) {
+	if ProgressEnabled() {
+		EmitProgress(ProgressEvent{Event: "error", Message: err.Error(), Path: path})
+		return
+	}
+	Printf("Error: %v (%s)\n", err, path)
+}
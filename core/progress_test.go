@@ -0,0 +1,108 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Enable progress events with new counts, and disable them after the test
+func enableTestProgress(t *testing.T) *bytes.Buffer {
+	events := &bytes.Buffer{}
+	progressDone, progressProcessed, progressFailed = 0, 0, 0
+	EnableProgress(events)
+	t.Cleanup(func() {
+		progressMutex.Lock()
+		defer progressMutex.Unlock()
+		progressWriter = nil
+	})
+	return events
+}
+
+// Write messages to a buffer during the test
+func setTestOutput(t *testing.T) *bytes.Buffer {
+	out := &bytes.Buffer{}
+	SetOutput(out)
+	t.Cleanup(func() { SetOutput(os.Stdout) })
+	return out
+}
+
+func readTestEvents(t *testing.T, events *bytes.Buffer) []ProgressEvent {
+	list := []ProgressEvent{}
+	for _, line := range strings.Split(strings.TrimSpace(events.String()), "\n") {
+		event := ProgressEvent{}
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("invalid event: %s", line)
+		}
+		list = append(list, event)
+	}
+	return list
+}
+
+func TestProgressEvents(t *testing.T) {
+	events := enableTestProgress(t)
+	out := setTestOutput(t)
+	start := time.Now()
+
+	EmitProgress(ProgressEvent{Event: "file-started", Path: "a.uasset"})
+	EmitFileDone("a.uasset", 3, start)
+	EmitFileDone("b.uasset", 2, start)
+	Warn("no %s", "entries")
+	PrintError(errors.New("broken"), "c.uasset")
+	EmitSummary(5, 1, start)
+
+	list := readTestEvents(t, events)
+	names := []string{}
+	for _, event := range list {
+		names = append(names, event.Event)
+	}
+	want := "file-started,file-done,file-done,warning,error,summary"
+	if strings.Join(names, ",") != want {
+		t.Fatalf("events: got %v, want %s", names, want)
+	}
+	if list[1].Done != 1 || list[2].Done != 2 || *list[2].Processed != 2 || list[2].DurationMs == nil {
+		t.Errorf("file-done: got %+v", list[2])
+	}
+	if list[3].Message != "no entries" || list[4].Message != "broken" || list[4].Path != "c.uasset" {
+		t.Errorf("messages: got %+v, %+v", list[3], list[4])
+	}
+	if *list[5].Processed != 5 || *list[5].Failed != 1 {
+		t.Errorf("summary: got %+v", list[5])
+	}
+
+	// Warnings and errors are not printed as messages
+	if out.Len() != 0 {
+		t.Errorf("output: got %q", out.String())
+	}
+}
+
+func TestFatalSummary(t *testing.T) {
+	events := enableTestProgress(t)
+	EmitFileDone("a.uasset", 3, time.Now())
+	EmitProgress(ProgressEvent{Event: "error", Path: "b.uasset", Message: "broken"})
+	EmitProgress(ProgressEvent{Event: "error", Message: "fatal"}) // not a file
+	events.Reset()
+
+	// Files done before the fatal error
+	emitFatalSummary()
+	list := readTestEvents(t, events)
+	if len(list) != 1 || list[0].Event != "summary" || *list[0].Processed != 3 || *list[0].Failed != 1 {
+		t.Errorf("fatal summary: got %s", events.String())
+	}
+}
+
+func TestOutputWithoutProgress(t *testing.T) {
+	out := setTestOutput(t)
+	Printf("Reading %s...\n", "a.uasset")
+	Println("Writing", "b.uasset...")
+	Warn("no %s", "entries")
+	PrintError(errors.New("broken"), "c.uasset")
+	want := "Reading a.uasset...\nWriting b.uasset...\nWarning: no entries\nError: broken (c.uasset)\n"
+	if out.String() != want {
+		t.Errorf("output: got %q, want %q", out.String(), want)
+	}
+}
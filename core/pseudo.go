@@ -0,0 +1,83 @@
+package core
+
+import (
+	"strings"
+)
+
+var PSEUDO_CHAR_MAP = map[rune]rune{
+	'A': 'Å', 'B': 'Ɓ', 'C': 'Ç', 'D': 'Đ', 'E': 'Ë', 'F': 'Ƒ', 'G': 'Ĝ',
+	'H': 'Ĥ', 'I': 'Ï', 'J': 'Ĵ', 'K': 'Ķ', 'L': 'Ļ', 'M': 'Ṁ', 'N': 'Ñ',
+	'O': 'Ö', 'P': 'Þ', 'Q': 'Ǫ', 'R': 'Ŕ', 'S': 'Š', 'T': 'Ţ', 'U': 'Û',
+	'V': 'Ṽ', 'W': 'Ŵ', 'X': 'Ẋ', 'Y': 'Ý', 'Z': 'Ž',
+	'a': 'å', 'b': 'ƀ', 'c': 'ç', 'd': 'đ', 'e': 'ë', 'f': 'ƒ', 'g': 'ĝ',
+	'h': 'ĥ', 'i': 'ï', 'j': 'ĵ', 'k': 'ķ', 'l': 'ļ', 'm': 'ṁ', 'n': 'ñ',
+	'o': 'ö', 'p': 'þ', 'q': 'ǫ', 'r': 'ŕ', 's': 'š', 't': 'ţ', 'u': 'û',
+	'v': 'ṽ', 'w': 'ŵ', 'x': 'ẋ', 'y': 'ý', 'z': 'ž',
+}
+
+type PseudoOptions struct {
+	Expansion int  // percentage of characters added to each line
+	Brackets  bool // wraps text with [ and ]
+}
+
+func pseudoPadding(count int, expansion int) string {
+	padding := (count*expansion + 99) / 100
+	return strings.Repeat("~", padding)
+}
+
+// Replace letters with accented ones and pad each line.
+// Tags, placeholders and line breaks are kept as-is.
+func Pseudolocalize(text string, opts *PseudoOptions) string {
+	builder := strings.Builder{}
+	if opts.Brackets {
+		builder.WriteString("[")
+	}
+	count := 0 // visible characters in the current line
+	for _, t := range Tokenize(text) {
+		if t.Kind == TOKEN_LINE_BREAK {
+			builder.WriteString(pseudoPadding(count, opts.Expansion))
+			builder.WriteString(t.Raw)
+			count = 0
+			continue
+		} else if t.Kind != TOKEN_TEXT && t.Kind != TOKEN_MALFORMED {
+			builder.WriteString(t.Raw)
+			continue
+		}
+		for _, r := range t.Raw {
+			if newR, ok := PSEUDO_CHAR_MAP[r]; ok {
+				r = newR
+			}
+			builder.WriteRune(r)
+			count++
+		}
+	}
+	builder.WriteString(pseudoPadding(count, opts.Expansion))
+	if opts.Brackets {
+		builder.WriteString("]")
+	}
+	return builder.String()
+}
+
+// Pseudo-localize all texts. It returns the number of entries that have edited texts or sub entries.
+func (uexp *Uexp) Pseudolocalize(opts *PseudoOptions) int {
+	count := 0
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		changed := false
+		if e.Text != "" && e.Text != e.Id {
+			e.Text = Pseudolocalize(e.Text, opts)
+			changed = true
+		}
+		for j := range len(e.SubEntries) {
+			se := &e.SubEntries[j]
+			if se.Text != "" {
+				se.Text = Pseudolocalize(se.Text, opts)
+				changed = true
+			}
+		}
+		if changed {
+			count++
+		}
+	}
+	return count
+}
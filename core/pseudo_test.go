@@ -0,0 +1,43 @@
+package core
+
+import "testing"
+
+func TestPseudolocalize(t *testing.T) {
+	tests := []struct {
+		text      string
+		expansion int
+		brackets  bool
+		want      string
+	}{
+		{"Cloud", 0, false, "Çļöûđ"},
+		{"Cloud", 30, false, "Çļöûđ~~"}, // rounded up
+		{"Cloud", 0, true, "[Çļöûđ]"},
+		{"ab\r\ncdef", 50, true, "[åƀ~\r\nçđëƒ~~]"}, // each line is padded
+		{"<Red>Hi</> {0}!", 0, false, "<Red>Ĥï</> {0}!"},
+		{"1 < 2", 0, false, "1 < 2"},
+		{"クラウド", 25, false, "クラウド~"},
+		{"", 0, true, "[]"},
+	}
+	for _, test := range tests {
+		opts := &PseudoOptions{Expansion: test.expansion, Brackets: test.brackets}
+		if got := Pseudolocalize(test.text, opts); got != test.want {
+			t.Errorf("Pseudolocalize(%q): got %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestPseudolocalizeUexp(t *testing.T) {
+	uexp := &Uexp{Entries: []Entry{
+		{Id: "a", Text: "Hi"},
+		{Id: "b", Text: "b"}, // text is id
+		{Id: "c", Text: "", SubEntries: []SubEntry{{Id: "ACTOR", Text: "Cloud"}}},
+		{Id: "d", Text: ""},
+	}}
+	count := uexp.Pseudolocalize(&PseudoOptions{})
+	if count != 2 {
+		t.Errorf("Pseudolocalize: got %d edited entries, want 2", count)
+	}
+	if uexp.Entries[1].Text != "b" || uexp.Entries[2].SubEntries[0].Text != "Çļöûđ" {
+		t.Errorf("Pseudolocalize: got %+v", uexp.Entries)
+	}
+}
@@ -0,0 +1,298 @@
+package core
+
+import (
+	"encoding/csv"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// Hepburn romanization for hiragana. Katakana is converted to hiragana before lookup.
+var HEPBURN_TABLE = map[string]string{
+	"あ": "a", "い": "i", "う": "u", "え": "e", "お": "o",
+	"か": "ka", "き": "ki", "く": "ku", "け": "ke", "こ": "ko",
+	"さ": "sa", "し": "shi", "す": "su", "せ": "se", "そ": "so",
+	"た": "ta", "ち": "chi", "つ": "tsu", "て": "te", "と": "to",
+	"な": "na", "に": "ni", "ぬ": "nu", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "hi", "ふ": "fu", "へ": "he", "ほ": "ho",
+	"ま": "ma", "み": "mi", "む": "mu", "め": "me", "も": "mo",
+	"や": "ya", "ゆ": "yu", "よ": "yo",
+	"ら": "ra", "り": "ri", "る": "ru", "れ": "re", "ろ": "ro",
+	"わ": "wa", "ゐ": "i", "ゑ": "e", "を": "o", "ん": "n",
+	"が": "ga", "ぎ": "gi", "ぐ": "gu", "げ": "ge", "ご": "go",
+	"ざ": "za", "じ": "ji", "ず": "zu", "ぜ": "ze", "ぞ": "zo",
+	"だ": "da", "ぢ": "ji", "づ": "zu", "で": "de", "ど": "do",
+	"ば": "ba", "び": "bi", "ぶ": "bu", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pi", "ぷ": "pu", "ぺ": "pe", "ぽ": "po",
+	"ゔ": "vu",
+	"ぁ": "a", "ぃ": "i", "ぅ": "u", "ぇ": "e", "ぉ": "o",
+	"ゃ": "ya", "ゅ": "yu", "ょ": "yo", "ゎ": "wa",
+	"きゃ": "kya", "きゅ": "kyu", "きょ": "kyo",
+	"しゃ": "sha", "しゅ": "shu", "しょ": "sho", "しぇ": "she",
+	"ちゃ": "cha", "ちゅ": "chu", "ちょ": "cho", "ちぇ": "che",
+	"にゃ": "nya", "にゅ": "nyu", "にょ": "nyo",
+	"ひゃ": "hya", "ひゅ": "hyu", "ひょ": "hyo",
+	"みゃ": "mya", "みゅ": "myu", "みょ": "myo",
+	"りゃ": "rya", "りゅ": "ryu", "りょ": "ryo",
+	"ぎゃ": "gya", "ぎゅ": "gyu", "ぎょ": "gyo",
+	"じゃ": "ja", "じゅ": "ju", "じょ": "jo", "じぇ": "je",
+	"ぢゃ": "ja", "ぢゅ": "ju", "ぢょ": "jo",
+	"びゃ": "bya", "びゅ": "byu", "びょ": "byo",
+	"ぴゃ": "pya", "ぴゅ": "pyu", "ぴょ": "pyo",
+	"ふぁ": "fa", "ふぃ": "fi", "ふぇ": "fe", "ふぉ": "fo",
+	"てぃ": "ti", "でぃ": "di", "とぅ": "tu", "どぅ": "du",
+	"うぃ": "wi", "うぇ": "we", "うぉ": "wo",
+	"ゔぁ": "va", "ゔぃ": "vi", "ゔぇ": "ve", "ゔぉ": "vo",
+}
+
+// Punctuation used in romanized lines
+var ROMANIZED_PUNCTUATION = map[rune]string{
+	'、': ", ", '。': ". ", '！': "! ", '？': "? ", '　': " ",
+	'「': "\"", '」': "\"", '『': "\"", '』': "\"", '（': "(", '）': ")",
+	'…': "...", '～': "~", '・': " ", '：': ": ",
+}
+
+func isKana(r rune) bool {
+	return unicode.In(r, unicode.Hiragana, unicode.Katakana) || r == 'ー'
+}
+
+func katakanaToHiragana(r rune) rune {
+	if r >= 'ァ' && r <= 'ヶ' {
+		return r - 'ァ' + 'ぁ'
+	}
+	return r
+}
+
+func lastVowel(s string) string {
+	for i := len(s) - 1; i >= 0; i-- {
+		if strings.ContainsRune("aeiou", rune(s[i])) {
+			return string(s[i])
+		}
+	}
+	return ""
+}
+
+// Convert a kana run to Hepburn romaji
+func RomanizeKana(kana string) string {
+	runes := []rune(kana)
+	for i := range len(runes) {
+		runes[i] = katakanaToHiragana(runes[i])
+	}
+	builder := strings.Builder{}
+	doubleNext := false
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		if r == 'っ' {
+			doubleNext = true
+			i++
+			continue
+		} else if r == 'ー' {
+			builder.WriteString(lastVowel(builder.String()))
+			i++
+			continue
+		}
+
+		// Try two-rune combinations first
+		roma := ""
+		if i+1 < len(runes) {
+			roma = HEPBURN_TABLE[string(runes[i:i+2])]
+		}
+		if roma != "" {
+			i += 2
+		} else {
+			roma = HEPBURN_TABLE[string(r)]
+			if roma == "" {
+				roma = string(r)
+			}
+			i++
+		}
+
+		if doubleNext && len(roma) > 0 && !strings.ContainsRune("aeiou", rune(roma[0])) {
+			if strings.HasPrefix(roma, "ch") {
+				builder.WriteString("t")
+			} else {
+				builder.WriteByte(roma[0])
+			}
+		}
+		doubleNext = false
+
+		// ん before a vowel or y is written as n'
+		if r == 'ん' && i < len(runes) {
+			next := HEPBURN_TABLE[string(katakanaToHiragana(runes[i]))]
+			if len(next) > 0 && strings.ContainsRune("aeiouy", rune(next[0])) {
+				roma = "n'"
+			}
+		}
+		builder.WriteString(roma)
+	}
+	return builder.String()
+}
+
+// Revised Romanization of Korean
+var HANGUL_INITIALS = []string{
+	"g", "kk", "n", "d", "tt", "r", "m", "b", "pp",
+	"s", "ss", "", "j", "jj", "ch", "k", "t", "p", "h",
+}
+
+var HANGUL_MEDIALS = []string{
+	"a", "ae", "ya", "yae", "eo", "e", "yeo", "ye", "o", "wa", "wae",
+	"oe", "yo", "u", "wo", "we", "wi", "yu", "eu", "ui", "i",
+}
+
+var HANGUL_FINALS = []string{
+	"", "k", "k", "k", "n", "n", "n", "t", "l", "k", "m", "l",
+	"l", "l", "p", "l", "m", "p", "p", "t", "t", "ng", "t", "t",
+	"k", "t", "p", "t",
+}
+
+// Finals moved to the next syllable that starts with ㅇ
+var HANGUL_LINKED_FINALS = []string{
+	"", "g", "kk", "gs", "n", "nj", "n", "d", "r", "lg", "lm", "lb",
+	"ls", "lt", "lp", "r", "m", "b", "bs", "s", "ss", "ng", "j", "ch",
+	"k", "t", "p", "",
+}
+
+var HANGUL_NASALIZED_FINALS = map[string]string{
+	"": "", "k": "ng", "t": "n", "p": "m", "n": "n", "l": "l", "m": "m", "ng": "ng",
+}
+
+func isHangulSyllable(r rune) bool {
+	return r >= 0xAC00 && r <= 0xD7A3
+}
+
+// Convert a run of Hangul syllables to Revised Romanization
+func RomanizeHangul(hangul string) string {
+	runes := []rune(hangul)
+	builder := strings.Builder{}
+	for i, r := range runes {
+		index := int(r - 0xAC00)
+		initial, medial, final := index/588, (index%588)/28, index%28
+		if i > 0 && isHangulSyllable(runes[i-1]) {
+			prevFinal := int(runes[i-1]-0xAC00) % 28
+			if prevFinal == 8 && (initial == 5 || initial == 2) {
+				builder.WriteString("l") // ㄹㄹ and ㄹㄴ are written as ll
+			} else if prevFinal == 0 || initial != 11 {
+				builder.WriteString(HANGUL_INITIALS[initial])
+			}
+		} else {
+			builder.WriteString(HANGUL_INITIALS[initial])
+		}
+		builder.WriteString(HANGUL_MEDIALS[medial])
+
+		if final == 0 {
+			continue
+		}
+		nextInitial := -1
+		if i+1 < len(runes) && isHangulSyllable(runes[i+1]) {
+			nextInitial = int(runes[i+1]-0xAC00) / 588
+		}
+		if nextInitial == 11 {
+			builder.WriteString(HANGUL_LINKED_FINALS[final])
+		} else if nextInitial == 2 || nextInitial == 6 {
+			// Nasalization before ㄴ and ㅁ
+			builder.WriteString(HANGUL_NASALIZED_FINALS[HANGUL_FINALS[final]])
+		} else {
+			builder.WriteString(HANGUL_FINALS[final])
+		}
+	}
+	return builder.String()
+}
+
+// Readings for kanji. Longer words are matched first.
+type ReadingDict struct {
+	readings  map[string]string
+	maxLength int
+}
+
+func NewReadingDict() *ReadingDict {
+	return &ReadingDict{readings: map[string]string{}}
+}
+
+// Load readings from csv (word,reading). Readings should be written in kana.
+func LoadReadingDict(filePath string) *ReadingDict {
+	dict := NewReadingDict()
+	Printf("Reading %s...\n", filePath)
+	file := OpenFile(filePath)
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			Throw(err)
+		} else if len(row) < 2 || row[0] == "" {
+			continue
+		}
+		dict.readings[row[0]] = row[1]
+		dict.maxLength = max(dict.maxLength, len([]rune(row[0])))
+	}
+	return dict
+}
+
+// Replace words in the dictionary with readings
+func (d *ReadingDict) Apply(text string) string {
+	if d == nil || len(d.readings) == 0 {
+		return text
+	}
+	runes := []rune(text)
+	builder := strings.Builder{}
+	for i := 0; i < len(runes); {
+		reading, n := matchLongest(runes, i, d.readings, d.maxLength)
+		if n == 0 {
+			builder.WriteRune(runes[i])
+			i++
+			continue
+		}
+		builder.WriteString(reading)
+		i += n
+	}
+	return builder.String()
+}
+
+var ROMANIZE_LANGS = []string{
+	"JP",
+	"KR",
+}
+
+// Romanize kana or Hangul runs in text as a line.
+// Kanji runs are kept as-is unless the dictionary has their readings.
+func Romanize(text string, lang string, dict *ReadingDict) string {
+	builder := strings.Builder{}
+	for _, t := range Tokenize(text) {
+		if t.Kind == TOKEN_LINE_BREAK {
+			builder.WriteString(" ")
+			continue
+		} else if t.Kind != TOKEN_TEXT && t.Kind != TOKEN_MALFORMED {
+			builder.WriteString(t.Raw)
+			continue
+		}
+
+		raw := t.Raw
+		if lang == "JP" {
+			raw = dict.Apply(raw)
+		}
+		runes := []rune(raw)
+		for i := 0; i < len(runes); {
+			j := i + 1
+			if lang == "JP" && isKana(runes[i]) {
+				for j < len(runes) && isKana(runes[j]) {
+					j++
+				}
+				builder.WriteString(RomanizeKana(string(runes[i:j])))
+			} else if lang == "KR" && isHangulSyllable(runes[i]) {
+				for j < len(runes) && isHangulSyllable(runes[j]) {
+					j++
+				}
+				builder.WriteString(RomanizeHangul(string(runes[i:j])))
+			} else if punc, ok := ROMANIZED_PUNCTUATION[runes[i]]; ok {
+				builder.WriteString(punc)
+			} else {
+				builder.WriteRune(runes[i])
+			}
+			i = j
+		}
+	}
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
@@ -0,0 +1,31 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadingDict(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.csv")
+	if err := os.WriteFile(path, []byte("神,かみ\n神羅,しんら\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	dict := LoadReadingDict(path)
+	tests := []struct {
+		text    string
+		applied string
+	}{
+		{"神羅の神", "しんらのかみ"}, // longer words first
+		{"羅", "羅"},
+		{"", ""},
+	}
+	for _, test := range tests {
+		if applied := dict.Apply(test.text); applied != test.applied {
+			t.Errorf("Apply(%q): got %q, want %q", test.text, applied, test.applied)
+		}
+	}
+	if roma := Romanize("神羅だ", "JP", dict); roma != "shinrada" {
+		t.Errorf("Romanize: got %q", roma)
+	}
+}
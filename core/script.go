@@ -0,0 +1,221 @@
+package core
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Languages that convert-script mode can convert between
+var SCRIPT_TARGETS = map[string]string{
+	"CN": "TW", // Simplified Chinese to Traditional Chinese
+	"TW": "CN",
+}
+
+// Find the longest word in words that starts at runes[i].
+// It returns the replacement and the length of the word (0 when not found).
+func matchLongest(runes []rune, i int, words map[string]string, maxLength int) (string, int) {
+	for n := min(maxLength, len(runes)-i); n > 0; n-- {
+		if value, ok := words[string(runes[i:i+n])]; ok {
+			return value, n
+		}
+	}
+	return "", 0
+}
+
+// Phrase and character mappings of OpenCC
+type ScriptDict struct {
+	words     map[string]string
+	maxLength int
+}
+
+func NewScriptDict() *ScriptDict {
+	return &ScriptDict{words: map[string]string{}}
+}
+
+func (d *ScriptDict) Add(key string, value string) {
+	d.words[key] = value
+	d.maxLength = max(d.maxLength, len([]rune(key)))
+}
+
+// Load a dictionary in OpenCC text format.
+// Each line has a key and space separated candidates. The first candidate is used.
+//
+//	头发	頭髮
+//	发	發 髮
+func (d *ScriptDict) LoadOpenCC(filePath string) {
+	Printf("Reading %s...\n", filePath)
+	file := OpenFile(filePath)
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, values, found := strings.Cut(line, "\t")
+		candidates := strings.Fields(values)
+		if !found || key == "" || len(candidates) == 0 {
+			Throw(fmt.Errorf("unexpected line detected in OpenCC dictionary. (%s)", line))
+		}
+		d.Add(key, candidates[0])
+	}
+	if err := scanner.Err(); err != nil {
+		Throw(err)
+	}
+}
+
+// Replace words with longest-match
+func (d *ScriptDict) Apply(text string) string {
+	if len(d.words) == 0 {
+		return text
+	}
+	runes := []rune(text)
+	builder := strings.Builder{}
+	for i := 0; i < len(runes); {
+		value, n := matchLongest(runes, i, d.words, d.maxLength)
+		if n == 0 {
+			builder.WriteRune(runes[i])
+			i++
+			continue
+		}
+		builder.WriteString(value)
+		i += n
+	}
+	return builder.String()
+}
+
+// Converter between Simplified and Traditional Chinese.
+// Dictionaries are applied stage by stage like OpenCC's conversion chain.
+// Overrides are applied first and their results are not converted again.
+type ScriptConverter struct {
+	overrides *ScriptDict
+	stages    []*ScriptDict
+}
+
+// Load dictionaries. Each stage has files merged into one dictionary.
+func LoadScriptConverter(stages [][]string, overridePath string) *ScriptConverter {
+	c := &ScriptConverter{overrides: NewScriptDict(), stages: []*ScriptDict{}}
+	for _, files := range stages {
+		dict := NewScriptDict()
+		for _, file := range files {
+			dict.LoadOpenCC(file)
+		}
+		c.stages = append(c.stages, dict)
+	}
+	if overridePath != "" {
+		c.LoadOverrides(overridePath)
+	}
+	return c
+}
+
+// Load overrides from csv (source,target)
+func (c *ScriptConverter) LoadOverrides(filePath string) {
+	Printf("Reading %s...\n", filePath)
+	file := OpenFile(filePath)
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			Throw(err)
+		} else if len(row) < 2 {
+			Throw("each row should has 2 items in override list")
+		}
+		if row[0] == "source" || row[0] == "" {
+			continue // header or empty row
+		}
+		c.overrides.Add(row[0], row[1])
+	}
+}
+
+func (c *ScriptConverter) convertRun(text string) string {
+	for _, dict := range c.stages {
+		text = dict.Apply(text)
+	}
+	return text
+}
+
+// Convert a text. Tags, placeholders and line breaks are kept as-is.
+func (c *ScriptConverter) Convert(text string) string {
+	builder := strings.Builder{}
+	for _, t := range Tokenize(text) {
+		if t.Kind != TOKEN_TEXT && t.Kind != TOKEN_MALFORMED {
+			builder.WriteString(t.Raw)
+			continue
+		}
+
+		// Split the run with overrides, then convert the rest
+		runes := []rune(t.Raw)
+		start := 0
+		for i := 0; i < len(runes); {
+			value, n := matchLongest(runes, i, c.overrides.words, c.overrides.maxLength)
+			if n == 0 {
+				i++
+				continue
+			}
+			builder.WriteString(c.convertRun(string(runes[start:i])))
+			builder.WriteString(value)
+			i += n
+			start = i
+		}
+		builder.WriteString(c.convertRun(string(runes[start:])))
+	}
+	return builder.String()
+}
+
+// Write converted texts of src into dst.
+// It returns the number of edited entries.
+func (c *ScriptConverter) ConvertUexp(src *Uexp, dst *Uexp) int {
+	target, ok := SCRIPT_TARGETS[src.Lang]
+	if !ok {
+		Warn("skipped an asset of unsupported language for script conversion. (%s)", src.Lang)
+		return 0
+	}
+	if dst.Lang != target {
+		Throw(fmt.Errorf("unexpected language detected. (expected %s, got %s)", target, dst.Lang))
+	}
+
+	count := 0
+	for i := range len(dst.Entries) {
+		e := &dst.Entries[i]
+		j := src.FindEntry(e.Id, min(i, len(src.Entries)-1))
+		if j < 0 {
+			continue
+		}
+		srcE := &src.Entries[j]
+		if srcE.Text == "" || srcE.Text == srcE.Id {
+			continue
+		}
+		changed := false
+		text := c.Convert(srcE.Text)
+		if text != e.Text {
+			e.Text = text
+			changed = true
+		}
+		for k := range len(e.SubEntries) {
+			se := &e.SubEntries[k]
+			for _, srcSe := range srcE.SubEntries {
+				if srcSe.Id == se.Id && srcSe.Text != "" {
+					text := c.Convert(srcSe.Text)
+					if text != se.Text {
+						se.Text = text
+						changed = true
+					}
+					break
+				}
+			}
+		}
+		if changed {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,48 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newTestScriptConverter(t *testing.T) *ScriptConverter {
+	path := filepath.Join(t.TempDir(), "STCharacters.txt")
+	if err := os.WriteFile(path, []byte("云\t雲 云\n剑\t劍\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadScriptConverter([][]string{{path}}, "")
+}
+
+func TestScriptConvert(t *testing.T) {
+	c := newTestScriptConverter(t)
+	if text := c.Convert("<Red>克劳德</>的剑\r\n{0}"); text != "<Red>克劳德</>的劍\r\n{0}" {
+		t.Errorf("Convert: got %q", text)
+	}
+}
+
+func TestConvertUexp(t *testing.T) {
+	c := newTestScriptConverter(t)
+	src := &Uexp{Lang: "CN", Entries: []Entry{
+		{Id: "a", Text: "剑", SubEntries: []SubEntry{{Id: "ACTOR", Text: "云"}}},
+		{Id: "b", Text: "好", SubEntries: []SubEntry{{Id: "ACTOR", Text: "云"}}},
+		{Id: "c", Text: "好"},
+	}}
+	dst := &Uexp{Lang: "TW", Entries: []Entry{
+		{Id: "a", Text: "", SubEntries: []SubEntry{{Id: "ACTOR", Text: ""}}},
+		{Id: "b", Text: "好", SubEntries: []SubEntry{{Id: "ACTOR", Text: ""}}}, // only the sub entry is changed
+		{Id: "c", Text: "好"},
+	}}
+	if count := c.ConvertUexp(src, dst); count != 2 {
+		t.Errorf("ConvertUexp: got %d, want 2", count)
+	}
+	if dst.Entries[0].Text != "劍" || dst.Entries[1].SubEntries[0].Text != "雲" {
+		t.Errorf("ConvertUexp: got %+v", dst.Entries)
+	}
+
+	// Other languages are skipped
+	src.Lang = "US"
+	if count := c.ConvertUexp(src, dst); count != 0 {
+		t.Errorf("ConvertUexp: got %d for US", count)
+	}
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"sync"
+	"unicode/utf8"
+)
+
+var SEARCH_FIELD_LIST = []string{
+	"text",
+	"id",
+	"sub",
+}
+
+type SearchQuery struct {
+	Pattern    *regexp.Regexp
+	Fields     []string // text, id or sub
+	Langs      []string // empty means all languages
+	Categories []string // empty means all categories
+}
+
+func NewSearchQuery(pattern string, isRegex bool, ignoreCase bool) *SearchQuery {
+	if !isRegex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if ignoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		Throw(err)
+	}
+	return &SearchQuery{
+		Pattern:    re,
+		Fields:     []string{"text"},
+		Langs:      []string{},
+		Categories: []string{},
+	}
+}
+
+func (q *SearchQuery) SetFields(fields []string) {
+	for _, field := range fields {
+		if !slices.Contains(SEARCH_FIELD_LIST, field) {
+			Throw(fmt.Errorf("unknown search field detected. (%s)", field))
+		}
+	}
+	q.Fields = fields
+}
+
+func (q *SearchQuery) SetLangs(langs []string) {
+	for _, lang := range langs {
+		if !slices.Contains(LANG_LIST, lang) {
+			Throw(fmt.Errorf("unknown language detected. (%s)", lang))
+		}
+	}
+	q.Langs = langs
+}
+
+func (q *SearchQuery) SetCategories(categories []string) {
+	q.Categories = categories
+}
+
+func (q *SearchQuery) MatchLang(lang string) bool {
+	return len(q.Langs) == 0 || slices.Contains(q.Langs, lang)
+}
+
+func (q *SearchQuery) MatchCategory(e *Entry) bool {
+	return len(q.Categories) == 0 || slices.Contains(q.Categories, e.GetCategory())
+}
+
+type SearchHit struct {
+	Path    string  `json:"path"`
+	Lang    string  `json:"language"`
+	Id      string  `json:"id"`
+	SubId   string  `json:"sub_id,omitempty"`
+	Field   string  `json:"field"`
+	Text    string  `json:"text"`
+	Matches [][]int `json:"matches"` // byte offsets of matches in text
+}
+
+// Get text around the first match.
+// width is the number of runes shown before and after the match.
+func (hit *SearchHit) GetContext(width int, highlightStart string, highlightEnd string) string {
+	start, end := hit.Matches[0][0], hit.Matches[0][1]
+	before := hit.Text[:start]
+	after := hit.Text[end:]
+	prefix, suffix := "", ""
+	if utf8.RuneCountInString(before) > width {
+		runes := []rune(before)
+		before = string(runes[len(runes)-width:])
+		prefix = "..."
+	}
+	if utf8.RuneCountInString(after) > width {
+		after = string([]rune(after)[:width])
+		suffix = "..."
+	}
+	context := prefix + before + highlightStart + hit.Text[start:end] + highlightEnd + after + suffix
+	return GoStrToCsvStr(context)
+}
+
+type SearchResult struct {
+	mutex sync.Mutex
+	Hits  []SearchHit
+}
+
+func NewSearchResult() *SearchResult {
+	return &SearchResult{Hits: []SearchHit{}}
+}
+
+func (r *SearchResult) match(hits []SearchHit, query *SearchQuery, hit SearchHit) []SearchHit {
+	matches := [][]int{}
+	for _, m := range query.Pattern.FindAllStringIndex(hit.Text, -1) {
+		// Skip zero-width matches (e.g. "^" or "a*") that highlight nothing
+		if m[0] < m[1] {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return hits
+	}
+	hit.Matches = matches
+	return append(hits, hit)
+}
+
+// Search entries. path is used for locations in the result.
+func (r *SearchResult) Search(uexp *Uexp, path string, query *SearchQuery) {
+	if !query.MatchLang(uexp.Lang) {
+		return
+	}
+	hits := []SearchHit{}
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		if !query.MatchCategory(e) {
+			continue
+		}
+		base := SearchHit{Path: path, Lang: uexp.Lang, Id: e.Id}
+		if slices.Contains(query.Fields, "id") {
+			hit := base
+			hit.Field = "id"
+			hit.Text = e.Id
+			hits = r.match(hits, query, hit)
+		}
+		if slices.Contains(query.Fields, "text") {
+			hit := base
+			hit.Field = "text"
+			hit.Text = e.Text
+			hits = r.match(hits, query, hit)
+		}
+		if slices.Contains(query.Fields, "sub") {
+			for _, se := range e.SubEntries {
+				hit := base
+				hit.Field = "sub"
+				hit.SubId = se.Id
+				hit.Text = se.Text
+				hits = r.match(hits, query, hit)
+			}
+		}
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Hits = append(r.Hits, hits...)
+}
+
+func (r *SearchResult) Sort() {
+	sort.SliceStable(r.Hits, func(i, j int) bool {
+		if r.Hits[i].Path != r.Hits[j].Path {
+			return r.Hits[i].Path < r.Hits[j].Path
+		}
+		return r.Hits[i].Id < r.Hits[j].Id
+	})
+}
+
+func (r *SearchResult) WriteAsText(w io.Writer, highlightStart string, highlightEnd string) {
+	for _, hit := range r.Hits {
+		id := hit.Id
+		if hit.SubId != "" {
+			id += " (" + hit.SubId + ")"
+		}
+		context := hit.GetContext(30, highlightStart, highlightEnd)
+		fmt.Fprintf(w, "%s: [%s] %s: %s\n", hit.Path, hit.Lang, id, context)
+	}
+	fmt.Fprintf(w, "%d hits\n", len(r.Hits))
+}
@@ -0,0 +1,41 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSearch(t *testing.T) {
+	uexp := &Uexp{Lang: "US", Entries: []Entry{
+		{Id: "MAIN_01", Text: "Cloud and Tifa"},
+		{Id: "MAIN_02", Text: "Barret", SubEntries: []SubEntry{{Id: "ACTOR", Text: "Cloud"}}},
+	}}
+	tests := []struct {
+		pattern string
+		isRegex bool
+		fields  []string
+		matches [][]int
+	}{
+		{"cloud", false, []string{"text"}, [][]int{{0, 5}}},
+		{"a.d", false, []string{"text"}, [][]int{}},
+		{"a.d", true, []string{"text"}, [][]int{{6, 9}}},
+		{"C[a-z]+", true, []string{"text", "sub"}, [][]int{{0, 5}, {0, 5}}},
+		// Zero-width matches are not hits
+		{"^", true, []string{"text"}, [][]int{}},
+		{"x*", true, []string{"text"}, [][]int{}},
+		{"Ti*", true, []string{"text"}, [][]int{{10, 12}, {5, 6}}},
+	}
+	for _, test := range tests {
+		query := NewSearchQuery(test.pattern, test.isRegex, true)
+		query.SetFields(test.fields)
+		result := NewSearchResult()
+		result.Search(uexp, "Text/Story", query)
+		matches := [][]int{}
+		for _, hit := range result.Hits {
+			matches = append(matches, hit.Matches...)
+		}
+		if !slices.EqualFunc(matches, test.matches, slices.Equal) {
+			t.Errorf("Search(%q): got %v, want %v", test.pattern, matches, test.matches)
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"unicode"
+)
+
+type TextStats struct {
+	Assets        int `json:"assets"`
+	Entries       int `json:"entries"`
+	SubEntries    int `json:"sub_entries"`
+	Chars         int `json:"chars"`
+	SubEntryChars int `json:"sub_entry_chars"`
+	Words         int `json:"words"`
+	Lines         int `json:"lines"`
+	Subtitles     int `json:"subtitles"`
+	NonSubtitles  int `json:"non_subtitles"`
+}
+
+var TEXT_STATS_COLUMNS = []string{
+	"assets", "entries", "sub_entries", "chars", "sub_entry_chars",
+	"words", "lines", "subtitles", "non_subtitles",
+}
+
+// Count words in text. Tags and placeholders are not words.
+// CJK text has no spaces between words, so each CJK character is counted as a word.
+func CountWords(text string) int {
+	count := 0
+	for _, t := range Tokenize(text) {
+		if t.Kind != TOKEN_TEXT {
+			continue
+		}
+		inWord := false
+		for _, r := range t.Raw {
+			if isCJK(r) {
+				count++
+				inWord = false
+			} else if unicode.IsSpace(r) {
+				inWord = false
+			} else if !inWord {
+				count++
+				inWord = true
+			}
+		}
+	}
+	return count
+}
+
+func (s *TextStats) AddEntry(e *Entry) {
+	s.Entries++
+	s.SubEntries += len(e.SubEntries)
+	s.Chars += e.CountLunes()
+	s.Words += CountWords(e.Text)
+	for _, se := range e.SubEntries {
+		s.SubEntryChars += len([]rune(se.Text))
+		s.Words += CountWords(se.Text)
+	}
+	if e.Text != "" {
+		s.Lines += e.CountLines()
+	}
+	if e.IsSubtitle() {
+		s.Subtitles++
+	} else {
+		s.NonSubtitles++
+	}
+}
+
+func (s *TextStats) Add(s2 *TextStats) {
+	s.Assets += s2.Assets
+	s.Entries += s2.Entries
+	s.SubEntries += s2.SubEntries
+	s.Chars += s2.Chars
+	s.SubEntryChars += s2.SubEntryChars
+	s.Words += s2.Words
+	s.Lines += s2.Lines
+	s.Subtitles += s2.Subtitles
+	s.NonSubtitles += s2.NonSubtitles
+}
+
+func (s *TextStats) ToRecord() []string {
+	values := []int{
+		s.Assets, s.Entries, s.SubEntries, s.Chars, s.SubEntryChars,
+		s.Words, s.Lines, s.Subtitles, s.NonSubtitles,
+	}
+	record := make([]string, 0, len(values))
+	for _, v := range values {
+		record = append(record, strconv.Itoa(v))
+	}
+	return record
+}
+
+type StatsReport struct {
+	mutex      sync.Mutex
+	Total      TextStats             `json:"total"`
+	Langs      map[string]*TextStats `json:"languages"`
+	Categories map[string]*TextStats `json:"categories"`
+	Dirs       map[string]*TextStats `json:"directories"`
+}
+
+func NewStatsReport() *StatsReport {
+	return &StatsReport{
+		Langs:      map[string]*TextStats{},
+		Categories: map[string]*TextStats{},
+		Dirs:       map[string]*TextStats{},
+	}
+}
+
+func getStats(group map[string]*TextStats, key string) *TextStats {
+	stats, ok := group[key]
+	if !ok {
+		stats = &TextStats{}
+		group[key] = stats
+	}
+	return stats
+}
+
+// Add an asset. dir is used for the breakdown by directory.
+func (r *StatsReport) Add(uexp *Uexp, dir string) {
+	assetStats := TextStats{Assets: 1}
+	categories := map[string]*TextStats{}
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		assetStats.AddEntry(e)
+		cat := e.GetCategory()
+		if cat == "" {
+			cat = "(none)"
+		}
+		getStats(categories, cat).AddEntry(e)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.Total.Add(&assetStats)
+	getStats(r.Langs, uexp.Lang).Add(&assetStats)
+	getStats(r.Dirs, dir).Add(&assetStats)
+	for cat, stats := range categories {
+		stats.Assets = 1
+		getStats(r.Categories, cat).Add(stats)
+	}
+}
+
+func sortedKeys(group map[string]*TextStats) []string {
+	keys := make([]string, 0, len(group))
+	for key := range group {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+type statsGroup struct {
+	Name  string
+	Key   string
+	Group map[string]*TextStats
+}
+
+func (r *StatsReport) getGroups() []statsGroup {
+	return []statsGroup{
+		{"Languages", "language", r.Langs},
+		{"Categories", "category", r.Categories},
+		{"Directories", "directory", r.Dirs},
+	}
+}
+
+func (r *StatsReport) WriteAsCsv(w *csv.Writer) {
+	header := append([]string{"group", "key"}, TEXT_STATS_COLUMNS...)
+	if err := w.Write(header); err != nil {
+		Throw(err)
+	}
+	if err := w.Write(append([]string{"total", ""}, r.Total.ToRecord()...)); err != nil {
+		Throw(err)
+	}
+	for _, g := range r.getGroups() {
+		for _, key := range sortedKeys(g.Group) {
+			record := append([]string{g.Key, key}, g.Group[key].ToRecord()...)
+			if err := w.Write(record); err != nil {
+				Throw(err)
+			}
+		}
+	}
+}
+
+func (r *StatsReport) SaveAsCsv(filePath string) {
+	Printf("Writing %s...\n", filePath)
+	file := CreateFile(filePath)
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+	r.WriteAsCsv(writer)
+	writer.Flush()
+}
+
+func (r *StatsReport) WriteAsText(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(tw, "group\tkey\t%s\t\n", strings.Join(TEXT_STATS_COLUMNS, "\t"))
+	fmt.Fprintf(tw, "total\t\t%s\t\n", strings.Join(r.Total.ToRecord(), "\t"))
+	for _, g := range r.getGroups() {
+		for _, key := range sortedKeys(g.Group) {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\n", g.Key, key, strings.Join(g.Group[key].ToRecord(), "\t"))
+		}
+	}
+	tw.Flush()
+}
+
+const STATS_HTML_TEMPLATE = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>ff7r-text-tool stats</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th:first-child, td:first-child { text-align: left; }
+th { background: #eee; }
+</style>
+</head>
+<body>
+<h1>Text statistics</h1>
+<table>
+<tr><th></th>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+<tr><td>total</td>{{range .Total}}<td>{{.}}</td>{{end}}</tr>
+</table>
+{{range .Groups}}
+<h2>{{.Name}}</h2>
+<table>
+<tr><th>{{.Key}}</th>{{range $.Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr><td>{{index . 0}}</td>{{range slice . 1}}<td>{{.}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`
+
+type statsHtmlGroup struct {
+	Name string
+	Key  string
+	Rows [][]string
+}
+
+func (r *StatsReport) SaveAsHtml(filePath string) {
+	tmpl, err := template.New("stats").Parse(STATS_HTML_TEMPLATE)
+	if err != nil {
+		Throw(err)
+	}
+	groups := []statsHtmlGroup{}
+	for _, g := range r.getGroups() {
+		hg := statsHtmlGroup{Name: g.Name, Key: g.Key, Rows: [][]string{}}
+		for _, key := range sortedKeys(g.Group) {
+			hg.Rows = append(hg.Rows, append([]string{key}, g.Group[key].ToRecord()...))
+		}
+		groups = append(groups, hg)
+	}
+
+	Printf("Writing %s...\n", filePath)
+	file := CreateFile(filePath)
+	defer file.Close()
+	err = tmpl.Execute(file, map[string]interface{}{
+		"Columns": TEXT_STATS_COLUMNS,
+		"Total":   r.Total.ToRecord(),
+		"Groups":  groups,
+	})
+	if err != nil {
+		Throw(err)
+	}
+}
@@ -0,0 +1,32 @@
+package core
+
+import "testing"
+
+func TestCountWords(t *testing.T) {
+	tests := []struct {
+		text  string
+		words int
+	}{
+		{"Let's go to the station.", 5},
+		{"<Red>Cloud</> and {0}", 2},
+		{"駅に行こう", 5},
+		{"クラウドと<Red>Tifa</>", 6},
+		{"Cloudは", 2},
+		{"は Cloud が", 3},
+		{"  a\r\nb  ", 2},
+		{"", 0},
+	}
+	for _, test := range tests {
+		if words := CountWords(test.text); words != test.words {
+			t.Errorf("CountWords(%q): got %d, want %d", test.text, words, test.words)
+		}
+	}
+}
+
+func TestStatsAddEntry(t *testing.T) {
+	s := &TextStats{}
+	s.AddEntry(&Entry{Id: "$story_MAIN_01", Text: "Hello world", SubEntries: []SubEntry{{Id: "ACTOR", Text: "クラウド"}}})
+	if s.Words != 6 || s.Chars != 11 || s.SubEntryChars != 4 {
+		t.Errorf("AddEntry: got %+v", s)
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"strings"
 	"unicode"
 	"unicode/utf16"
 )
@@ -42,3 +43,15 @@ func isASCII(s string) bool {
 	}
 	return true
 }
+
+// Split comma separated values
+func SplitList(str string) []string {
+	list := []string{}
+	for _, s := range strings.Split(str, ",") {
+		s = strings.TrimSpace(s)
+		if s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
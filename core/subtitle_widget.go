@@ -1,45 +1,101 @@
 package core
 
 import (
-	"bytes"
+	"encoding/binary"
 	"fmt"
+	"math"
 )
 
+// Size of the subtitle widget in the original assets
+const SUBTITLE_WIDGET_WIDTH = 930
+const SUBTITLE_WIDGET_HEIGHT = 210
+
+// Paths of float properties that have the width or the height of the subtitle widget
+var SUBTITLE_WIDTH_PATHS = []string{
+	"LayoutData.Offsets.Right", // CanvasPanelSlot
+	"WrapTextAt",
+	"WidthOverride",
+	"MinDesiredWidth",
+}
+
+var SUBTITLE_HEIGHT_PATHS = []string{
+	"LayoutData.Offsets.Bottom", // CanvasPanelSlot
+	"HeightOverride",
+	"MinDesiredHeight",
+}
+
+// Offsets of the size values in Subtitle00.uasset of FF7R2.
+// FF7R2 uses unversioned properties that have no tags, so the values are read at these offsets when mappings are not set.
+var FF7R2_SUBTITLE_WIDTH_OFFSETS = []int{36459, 38688}
+var FF7R2_SUBTITLE_HEIGHT_OFFSETS = []int{36488}
+
+// Make patches for float properties that have the original size.
+// Properties are located in the same way as patch-widget mode.
+func (pkg *PackageAsset) makeSizePatches(assetName string, paths []string, origValue int, newValue int) []WidgetPatch {
+	patches := []WidgetPatch{}
+	for i := range len(pkg.Exports) {
+		export := &pkg.Exports[i]
+		for _, path := range paths {
+			v, err := pkg.FindProperty(export, path)
+			if err != nil || v.Type != "FloatProperty" || pkg.GetValue(v).(float64) != float64(origValue) {
+				continue
+			}
+			patches = append(patches, WidgetPatch{
+				Asset:  assetName,
+				Widget: export.Name,
+				Path:   path,
+				Type:   v.Type,
+				Old:    float64(origValue),
+				New:    float64(newValue),
+			})
+		}
+	}
+	return patches
+}
+
+// Edit float values at the known offsets (FF7R2 without mappings).
+// It returns false when any of them does not have the original size.
+func (pkg *PackageAsset) patchSizeValues(offsets []int, origValue int, newValue int) bool {
+	le := binary.LittleEndian
+	for _, offset := range offsets {
+		if offset < 0 || offset+4 > len(pkg.Data) || math.Float32frombits(le.Uint32(pkg.Data[offset:])) != float32(origValue) {
+			return false
+		}
+	}
+	for _, offset := range offsets {
+		Printf("  float at %d: %d -> %d\n", offset, origValue, newValue)
+		le.PutUint32(pkg.Data[offset:], math.Float32bits(float32(newValue)))
+	}
+	return true
+}
+
 // Edit Subtitle00.uasset to resize subtitle widget
 // The original asset uses 930 x 210
 // My dual subtitle mod uses 1170 x 260
-func ResizeSubtitleWidget(filePath string, outPath string, width int, height int) {
-	s := NewSerializer()
-
-	// Open files
-	fmt.Printf("Reading %s...\n", filePath)
-	uassetFile := OpenFile(filePath)
-	defer uassetFile.Close()
-
-	s.SetReadFile(uassetFile)
-	signature := s.Read(4)
-	ver := VER_FF7R
-	if !bytes.Equal(signature, UNREAL_SIGNATURE) {
-		ver = VER_FF7R2
+func (pkg *PackageAsset) ResizeSubtitleWidget(assetName string, width int, height int) {
+	unrecognized := func(widthCount int, heightCount int) {
+		Throw(fmt.Errorf(
+			"unrecognized subtitle widget layout. (%d width and %d height values with the original size %dx%d found. the asset might be edited already.)",
+			widthCount, heightCount, SUBTITLE_WIDGET_WIDTH, SUBTITLE_WIDGET_HEIGHT,
+		))
+	}
+
+	// Without mappings, FF7R2 values are edited at the known offsets
+	if pkg.HasUnversionedProperties() && pkg.reader == nil {
+		if !pkg.patchSizeValues(FF7R2_SUBTITLE_WIDTH_OFFSETS, SUBTITLE_WIDGET_WIDTH, width) {
+			unrecognized(0, 0)
+		}
+		if !pkg.patchSizeValues(FF7R2_SUBTITLE_HEIGHT_OFFSETS, SUBTITLE_WIDGET_HEIGHT, height) {
+			unrecognized(len(FF7R2_SUBTITLE_WIDTH_OFFSETS), 0)
+		}
+		return
 	}
-	s.Seek(0, 0)
-	bin := s.ReadAll()
-
-	fmt.Printf("Writing %s...\n", outPath)
-	newFile := CreateFile(outPath)
-	defer newFile.Close()
-
-	s.SetWriteFile(newFile)
-	s.Write(bin)
-
-	if ver == VER_FF7R2 {
-		s.Seek(36459, 0)
-		s.WriteFloat32(float32(width))
-		s.Seek(36488, 0)
-		s.WriteFloat32(float32(height))
-		s.Seek(38688, 0)
-		s.WriteFloat32(float32(width))
-	} else {
-		Throw("Sorry. This feature only supports FF7R2 for now")
+
+	// Resize is a built-in patch for patch-widget mode
+	widthPatches := pkg.makeSizePatches(assetName, SUBTITLE_WIDTH_PATHS, SUBTITLE_WIDGET_WIDTH, width)
+	heightPatches := pkg.makeSizePatches(assetName, SUBTITLE_HEIGHT_PATHS, SUBTITLE_WIDGET_HEIGHT, height)
+	if len(widthPatches) == 0 || len(heightPatches) == 0 {
+		unrecognized(len(widthPatches), len(heightPatches))
 	}
+	pkg.ApplyPatches(append(widthPatches, heightPatches...), assetName)
 }
@@ -0,0 +1,48 @@
+//go:build !windows
+
+package core
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+func runStty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// Disable line buffering and echo of the terminal.
+// It returns a function to restore the previous state.
+func enableRawMode() (func(), error) {
+	state, err := runStty("-g")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runStty("raw", "-echo"); err != nil {
+		return nil, err
+	}
+	return func() { runStty(state) }, nil
+}
+
+// Get the number of columns and rows of the terminal
+func getTerminalSize() (int, int) {
+	out, err := runStty("size")
+	if err != nil {
+		return 80, 24
+	}
+	fields := strings.Fields(out)
+	if len(fields) != 2 {
+		return 80, 24
+	}
+	rows, err1 := strconv.Atoi(fields[0])
+	cols, err2 := strconv.Atoi(fields[1])
+	if err1 != nil || err2 != nil || rows <= 0 || cols <= 0 {
+		return 80, 24
+	}
+	return cols, rows
+}
@@ -0,0 +1,85 @@
+//go:build windows
+
+package core
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	ENABLE_PROCESSED_INPUT             = 0x0001
+	ENABLE_LINE_INPUT                  = 0x0002
+	ENABLE_ECHO_INPUT                  = 0x0004
+	ENABLE_VIRTUAL_TERMINAL_INPUT      = 0x0200
+	ENABLE_VIRTUAL_TERMINAL_PROCESSING = 0x0004
+)
+
+var (
+	kernel32                       = syscall.NewLazyDLL("kernel32.dll")
+	procGetConsoleMode             = kernel32.NewProc("GetConsoleMode")
+	procSetConsoleMode             = kernel32.NewProc("SetConsoleMode")
+	procGetConsoleScreenBufferInfo = kernel32.NewProc("GetConsoleScreenBufferInfo")
+)
+
+type consoleScreenBufferInfo struct {
+	size              [2]int16
+	cursorPosition    [2]int16
+	attributes        uint16
+	window            [4]int16 // left, top, right, bottom
+	maximumWindowSize [2]int16
+}
+
+func getConsoleMode(handle uintptr) (uint32, error) {
+	var mode uint32
+	r, _, err := procGetConsoleMode.Call(handle, uintptr(unsafe.Pointer(&mode)))
+	if r == 0 {
+		return 0, err
+	}
+	return mode, nil
+}
+
+func setConsoleMode(handle uintptr, mode uint32) error {
+	r, _, err := procSetConsoleMode.Call(handle, uintptr(mode))
+	if r == 0 {
+		return err
+	}
+	return nil
+}
+
+// Disable line buffering and echo of the console, and enable escape sequences.
+// It returns a function to restore the previous state.
+func enableRawMode() (func(), error) {
+	stdin, stdout := os.Stdin.Fd(), os.Stdout.Fd()
+	inMode, err := getConsoleMode(stdin)
+	if err != nil {
+		return nil, err
+	}
+	outMode, err := getConsoleMode(stdout)
+	if err != nil {
+		return nil, err
+	}
+	newInMode := inMode&^(ENABLE_PROCESSED_INPUT|ENABLE_LINE_INPUT|ENABLE_ECHO_INPUT) | ENABLE_VIRTUAL_TERMINAL_INPUT
+	if err := setConsoleMode(stdin, newInMode); err != nil {
+		return nil, err
+	}
+	if err := setConsoleMode(stdout, outMode|ENABLE_VIRTUAL_TERMINAL_PROCESSING); err != nil {
+		setConsoleMode(stdin, inMode)
+		return nil, err
+	}
+	return func() {
+		setConsoleMode(stdin, inMode)
+		setConsoleMode(stdout, outMode)
+	}, nil
+}
+
+// Get the number of columns and rows of the console
+func getTerminalSize() (int, int) {
+	info := consoleScreenBufferInfo{}
+	r, _, _ := procGetConsoleScreenBufferInfo.Call(os.Stdout.Fd(), uintptr(unsafe.Pointer(&info)))
+	if r == 0 {
+		return 80, 24
+	}
+	return int(info.window[2]-info.window[0]) + 1, int(info.window[3]-info.window[1]) + 1
+}
@@ -0,0 +1,578 @@
+package core
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+type tuiMode int
+
+const (
+	TUI_LIST tuiMode = iota
+	TUI_FILTER
+	TUI_EDIT
+	TUI_SAVE_AS
+)
+
+const TUI_HELP_LIST = "Up/Down:move  Enter:edit  /:filter  u:undo  s:save as  q:quit"
+const TUI_HELP_EDIT = "Tab/Up/Down:field  Enter:apply  Esc:cancel  <br> is a line break"
+const TUI_HELP_INPUT = "Enter:ok  Esc:cancel"
+
+// A key pressed in the terminal. name is empty for printable characters.
+type tuiKey struct {
+	name string
+	r    rune
+}
+
+// Parse input bytes into keys. Escape sequences should be in the same chunk.
+func parseKeys(data []byte) []tuiKey {
+	sequences := map[string]string{
+		"[A": "up", "[B": "down", "[C": "right", "[D": "left",
+		"[H": "home", "[F": "end", "OH": "home", "OF": "end",
+		"[1~": "home", "[4~": "end", "[3~": "delete",
+		"[5~": "pgup", "[6~": "pgdn", "[Z": "backtab",
+	}
+	keys := []tuiKey{}
+	for len(data) > 0 {
+		c := data[0]
+		switch {
+		case c == 0x1b:
+			name := "esc"
+			size := 1
+			for seq, key := range sequences {
+				if strings.HasPrefix(string(data[1:]), seq) {
+					name = key
+					size = 1 + len(seq)
+					break
+				}
+			}
+			keys = append(keys, tuiKey{name: name})
+			data = data[size:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, tuiKey{name: "enter"})
+		case c == 0x7f || c == 0x08:
+			keys = append(keys, tuiKey{name: "backspace"})
+		case c == '\t':
+			keys = append(keys, tuiKey{name: "tab"})
+		case c == 0x03:
+			keys = append(keys, tuiKey{name: "ctrl-c"})
+		case c == 0x1a:
+			keys = append(keys, tuiKey{name: "ctrl-z"})
+		case c < 0x20:
+			// Ignore other control characters
+		default:
+			r, size := utf8.DecodeRune(data)
+			keys = append(keys, tuiKey{r: r})
+			data = data[size:]
+			continue
+		}
+		data = data[1:]
+	}
+	return keys
+}
+
+// Edit a single line input. It returns false when the key is not for text input.
+func editRunes(buf []rune, caret int, key tuiKey) ([]rune, int, bool) {
+	switch key.name {
+	case "":
+		buf = slices.Insert(buf, caret, key.r)
+		caret++
+	case "left":
+		caret = max(caret-1, 0)
+	case "right":
+		caret = min(caret+1, len(buf))
+	case "home":
+		caret = 0
+	case "end":
+		caret = len(buf)
+	case "backspace":
+		if caret > 0 {
+			buf = slices.Delete(buf, caret-1, caret)
+			caret--
+		}
+	case "delete":
+		if caret < len(buf) {
+			buf = slices.Delete(buf, caret, caret+1)
+		}
+	default:
+		return buf, caret, false
+	}
+	return buf, caret, true
+}
+
+func displayRune(r rune) rune {
+	if r < 0x20 || r == 0x7f {
+		return '?'
+	}
+	return r
+}
+
+func runesWidth(runes []rune) int {
+	width := 0
+	for _, r := range runes {
+		width += RuneWidth(r)
+	}
+	return width
+}
+
+// Cut or pad a string to fit the display width
+func fitWidth(str string, width int) string {
+	b := strings.Builder{}
+	w := 0
+	for _, r := range str {
+		rw := RuneWidth(r)
+		if w+rw > width {
+			break
+		}
+		b.WriteRune(displayRune(r))
+		w += rw
+	}
+	b.WriteString(strings.Repeat(" ", width-w))
+	return b.String()
+}
+
+// A text field in the edit screen
+type tuiField struct {
+	label string
+	subId string // empty for the main text
+	value []rune // line breaks are shown as <br>
+	orig  string
+}
+
+type tuiUndo struct {
+	index int
+	entry Entry
+}
+
+// Full screen terminal editor for an asset
+type Tui struct {
+	uasset       *Uasset
+	path         string // the original asset. it is never overwritten.
+	orig         []Entry
+	mode         tuiMode
+	filter       []rune
+	indices      []int // filtered entries
+	cursor       int   // position in indices
+	scroll       int
+	fields       []tuiField
+	field        int
+	caret        int
+	input        []rune // path for save-as
+	undo         []tuiUndo
+	changes      int
+	savedChanges int
+	savePath     string
+	confirmQuit  bool
+	message      string
+	width        int
+	height       int
+}
+
+func NewTui(path string, savePath string) *Tui {
+	uasset := &Uasset{}
+	uasset.ReadFromFile(path)
+	tui := &Tui{
+		uasset:   uasset,
+		path:     path,
+		orig:     copyEntries(uasset.Uexp.Entries),
+		savePath: savePath,
+		width:    80,
+		height:   24,
+	}
+	tui.updateFilter()
+	return tui
+}
+
+func (tui *Tui) IsModified() bool {
+	return tui.changes != tui.savedChanges
+}
+
+func (tui *Tui) updateFilter() {
+	filter := strings.ToLower(string(tui.filter))
+	tui.indices = []int{}
+	for i, e := range tui.uasset.Uexp.Entries {
+		if filter == "" || strings.Contains(strings.ToLower(e.Id), filter) ||
+			strings.Contains(strings.ToLower(GoStrToCsvStr(e.Text)), filter) {
+			tui.indices = append(tui.indices, i)
+		}
+	}
+	tui.cursor = min(tui.cursor, max(len(tui.indices)-1, 0))
+}
+
+func (tui *Tui) listRows() int {
+	return max(tui.height-3, 1)
+}
+
+func (tui *Tui) moveCursor(delta int) {
+	if len(tui.indices) == 0 {
+		return
+	}
+	tui.cursor = min(max(tui.cursor+delta, 0), len(tui.indices)-1)
+}
+
+func (tui *Tui) startEdit() {
+	if len(tui.indices) == 0 {
+		return
+	}
+	i := tui.indices[tui.cursor]
+	e := &tui.uasset.Uexp.Entries[i]
+	tui.fields = []tuiField{{label: "text", value: []rune(GoStrToCsvStr(e.Text)), orig: tui.orig[i].Text}}
+	for j, se := range e.SubEntries {
+		tui.fields = append(tui.fields, tuiField{
+			label: "sub " + se.Id,
+			subId: se.Id,
+			value: []rune(GoStrToCsvStr(se.Text)),
+			orig:  tui.orig[i].SubEntries[j].Text,
+		})
+	}
+	tui.field = 0
+	tui.caret = len(tui.fields[0].value)
+	tui.mode = TUI_EDIT
+}
+
+func (tui *Tui) applyEdit() {
+	i := tui.indices[tui.cursor]
+	e := &tui.uasset.Uexp.Entries[i]
+	newEntry := &Entry{Id: e.Id, SubEntries: []SubEntry{}}
+	for _, f := range tui.fields {
+		text := CsvStrToGoStr(string(f.value))
+		if f.subId == "" {
+			newEntry.Text = text
+		} else {
+			newEntry.SubEntries = append(newEntry.SubEntries, SubEntry{Id: f.subId, Text: text})
+		}
+	}
+	tui.mode = TUI_LIST
+	edited := *e
+	edited.SubEntries = slices.Clone(e.SubEntries)
+	edited.UpdateWithNewEntry(newEntry)
+	if !entryIsModified(&edited, e) {
+		tui.message = "No changes."
+		return
+	}
+	tui.undo = append(tui.undo, tuiUndo{index: i, entry: *e})
+	*e = edited
+	tui.changes++
+
+	warnings := CompareMarkup(tui.orig[i].Text, e.Text)
+	for j, se := range e.SubEntries {
+		for _, msg := range CompareMarkup(tui.orig[i].SubEntries[j].Text, se.Text) {
+			warnings = append(warnings, se.Id+": "+msg)
+		}
+	}
+	if len(warnings) > 0 {
+		tui.message = "Warning: " + strings.Join(warnings, ", ")
+	} else {
+		tui.message = "Edited " + e.Id
+	}
+	tui.updateFilter()
+}
+
+func (tui *Tui) undoEdit() {
+	if len(tui.undo) == 0 {
+		tui.message = "Nothing to undo."
+		return
+	}
+	last := tui.undo[len(tui.undo)-1]
+	tui.undo = tui.undo[:len(tui.undo)-1]
+	tui.uasset.Uexp.Entries[last.index] = last.entry
+	tui.changes++
+	tui.message = "Undid an edit of " + last.entry.Id
+	tui.updateFilter()
+	if j := slices.Index(tui.indices, last.index); j >= 0 {
+		tui.cursor = j
+	}
+}
+
+func isSameFile(path1 string, path2 string) bool {
+	if filepath.Clean(path1) == filepath.Clean(path2) {
+		return true
+	}
+	stat1, err1 := os.Stat(path1)
+	stat2, err2 := os.Stat(path2)
+	return err1 == nil && err2 == nil && os.SameFile(stat1, stat2)
+}
+
+// Write the asset to a new path. It refuses to overwrite the original asset.
+func (tui *Tui) SaveAs(path string) error {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	if filepath.Ext(path) != ".uasset" {
+		return fmt.Errorf("not .uasset. (%s)", path)
+	}
+	if isSameFile(path, tui.path) || isSameFile(RemoveExtension(path)+".uexp", RemoveExtension(tui.path)+".uexp") {
+		return fmt.Errorf("can not overwrite the original asset. (%s)", path)
+	}
+	err = Try(func() {
+		MakeDir(filepath.Dir(path))
+		tui.uasset.WriteToFile(path)
+	})
+	if err != nil {
+		return err
+	}
+	tui.savePath = path
+	tui.savedChanges = tui.changes
+	return nil
+}
+
+// Handle a key. It returns true to quit.
+func (tui *Tui) HandleKey(key tuiKey) bool {
+	if key.r != 'q' && key.name != "ctrl-c" {
+		tui.confirmQuit = false
+	}
+	switch tui.mode {
+	case TUI_LIST:
+		tui.message = ""
+		switch {
+		case key.name == "up":
+			tui.moveCursor(-1)
+		case key.name == "down":
+			tui.moveCursor(1)
+		case key.name == "pgup":
+			tui.moveCursor(-tui.listRows())
+		case key.name == "pgdn":
+			tui.moveCursor(tui.listRows())
+		case key.name == "home":
+			tui.moveCursor(-len(tui.indices))
+		case key.name == "end":
+			tui.moveCursor(len(tui.indices))
+		case key.name == "enter":
+			tui.startEdit()
+		case key.r == '/':
+			tui.mode = TUI_FILTER
+			tui.caret = len(tui.filter)
+		case key.r == 'u' || key.name == "ctrl-z":
+			tui.undoEdit()
+		case key.r == 's':
+			tui.mode = TUI_SAVE_AS
+			tui.input = []rune(tui.savePath)
+			tui.caret = len(tui.input)
+		case key.r == 'q' || key.name == "ctrl-c":
+			if !tui.IsModified() || tui.confirmQuit {
+				return true
+			}
+			tui.confirmQuit = true
+			tui.message = "Unsaved changes. Press q again to quit."
+		}
+	case TUI_FILTER:
+		switch key.name {
+		case "enter":
+			tui.mode = TUI_LIST
+		case "esc", "ctrl-c":
+			tui.filter = []rune{}
+			tui.mode = TUI_LIST
+			tui.updateFilter()
+		case "up":
+			tui.moveCursor(-1)
+		case "down":
+			tui.moveCursor(1)
+		default:
+			var ok bool
+			tui.filter, tui.caret, ok = editRunes(tui.filter, tui.caret, key)
+			if ok {
+				tui.cursor = 0
+				tui.updateFilter()
+			}
+		}
+	case TUI_EDIT:
+		switch key.name {
+		case "enter":
+			tui.applyEdit()
+		case "esc", "ctrl-c":
+			tui.mode = TUI_LIST
+			tui.message = "Canceled."
+		case "tab", "down":
+			tui.field = (tui.field + 1) % len(tui.fields)
+			tui.caret = len(tui.fields[tui.field].value)
+		case "backtab", "up":
+			tui.field = (tui.field + len(tui.fields) - 1) % len(tui.fields)
+			tui.caret = len(tui.fields[tui.field].value)
+		default:
+			f := &tui.fields[tui.field]
+			f.value, tui.caret, _ = editRunes(f.value, tui.caret, key)
+		}
+	case TUI_SAVE_AS:
+		switch key.name {
+		case "enter":
+			tui.mode = TUI_LIST
+			if err := tui.SaveAs(string(tui.input)); err != nil {
+				tui.message = "Error: " + err.Error()
+			} else {
+				tui.message = "Saved " + tui.savePath
+			}
+		case "esc", "ctrl-c":
+			tui.mode = TUI_LIST
+			tui.message = "Canceled."
+		default:
+			tui.input, tui.caret, _ = editRunes(tui.input, tui.caret, key)
+		}
+	}
+	return false
+}
+
+// Split runes into rows of the display width. It also returns the row and column of the caret.
+func wrapRunes(runes []rune, width int, caret int) ([]string, int, int) {
+	rows := []string{}
+	row := []rune{}
+	w := 0
+	caretRow, caretCol := 0, 0
+	for i, r := range runes {
+		rw := RuneWidth(r)
+		if w+rw > width && len(row) > 0 {
+			rows = append(rows, string(row))
+			row = []rune{}
+			w = 0
+		}
+		if i == caret {
+			caretRow, caretCol = len(rows), w
+		}
+		row = append(row, displayRune(r))
+		w += rw
+	}
+	if caret >= len(runes) {
+		if w >= width {
+			rows = append(rows, string(row))
+			row = []rune{}
+			w = 0
+		}
+		caretRow, caretCol = len(rows), w
+	}
+	rows = append(rows, string(row))
+	return rows, caretRow, caretCol
+}
+
+func (tui *Tui) renderList(lines []string) []string {
+	rows := tui.listRows()
+	if tui.cursor < tui.scroll {
+		tui.scroll = tui.cursor
+	} else if tui.cursor >= tui.scroll+rows {
+		tui.scroll = tui.cursor - rows + 1
+	}
+	idWidth := min(32, tui.width/3)
+	for r := range rows {
+		pos := tui.scroll + r
+		if pos >= len(tui.indices) {
+			lines = append(lines, "")
+			continue
+		}
+		i := tui.indices[pos]
+		e := &tui.uasset.Uexp.Entries[i]
+		mark := "  "
+		if entryIsModified(e, &tui.orig[i]) {
+			mark = "* "
+		}
+		line := mark + fitWidth(e.Id, idWidth) + " " + fitWidth(GoStrToCsvStr(e.Text), max(tui.width-idWidth-3, 0))
+		if pos == tui.cursor {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func (tui *Tui) renderEdit(lines []string) ([]string, int, int) {
+	e := &tui.uasset.Uexp.Entries[tui.indices[tui.cursor]]
+	lines = append(lines, fitWidth("Entry: "+e.Id, tui.width))
+	caretRow, caretCol := 0, 0
+	for j, f := range tui.fields {
+		label := "[" + f.label + "]"
+		if j == tui.field {
+			label = "\x1b[1m" + label + " <\x1b[0m"
+		}
+		lines = append(lines, label)
+		caret := len(f.value)
+		if j == tui.field {
+			caret = tui.caret
+		}
+		rows, row, col := wrapRunes(f.value, max(tui.width-2, 1), caret)
+		if j == tui.field {
+			caretRow, caretCol = len(lines)+row, col+2
+		}
+		for _, r := range rows {
+			lines = append(lines, "  "+r)
+		}
+		if CsvStrToGoStr(string(f.value)) != f.orig {
+			lines = append(lines, "\x1b[2m  orig: "+fitWidth(GoStrToCsvStr(f.orig), max(tui.width-8, 0))+"\x1b[0m")
+		}
+	}
+	for len(lines) < tui.height-2 {
+		lines = append(lines, "")
+	}
+	return lines[:max(tui.height-2, 1)], caretRow, caretCol
+}
+
+// Draw the screen
+func (tui *Tui) Render(w io.Writer) {
+	header := fmt.Sprintf("%s (%s)  %d/%d entries", filepath.Base(tui.path), tui.uasset.Uexp.Lang, len(tui.indices), len(tui.uasset.Uexp.Entries))
+	if tui.IsModified() {
+		header += "  [modified]"
+	}
+	lines := []string{"\x1b[7m" + fitWidth(header, tui.width) + "\x1b[0m"}
+
+	caretRow, caretCol := -1, 0
+	status := tui.message
+	help := TUI_HELP_LIST
+	switch tui.mode {
+	case TUI_LIST:
+		lines = tui.renderList(lines)
+	case TUI_FILTER:
+		lines = tui.renderList(lines)
+		status = "/" + string(tui.filter)
+		caretRow, caretCol = len(lines), 1+runesWidth(tui.filter[:tui.caret])
+		help = TUI_HELP_INPUT
+	case TUI_EDIT:
+		lines, caretRow, caretCol = tui.renderEdit(lines)
+		help = TUI_HELP_EDIT
+	case TUI_SAVE_AS:
+		lines = tui.renderList(lines)
+		status = "Save as: " + string(tui.input)
+		caretRow, caretCol = len(lines), 9+runesWidth(tui.input[:tui.caret])
+		help = TUI_HELP_INPUT
+	}
+	lines = append(lines, fitWidth(status, tui.width), "\x1b[2m"+fitWidth(help, tui.width)+"\x1b[0m")
+
+	b := strings.Builder{}
+	b.WriteString("\x1b[?25l\x1b[H\x1b[2J")
+	b.WriteString(strings.Join(lines, "\r\n"))
+	if caretRow >= 0 {
+		b.WriteString(fmt.Sprintf("\x1b[%d;%dH\x1b[?25h", caretRow+1, min(caretCol, tui.width-1)+1))
+	}
+	w.Write([]byte(b.String()))
+}
+
+// Run the editor in the terminal until quit
+func (tui *Tui) Run() {
+	restore, err := enableRawMode()
+	if err != nil {
+		Throw(fmt.Errorf("failed to use the terminal. %v", err))
+	}
+	out := os.Stdout
+	out.WriteString("\x1b[?1049h")
+	defer func() {
+		out.WriteString("\x1b[?25h\x1b[?1049l")
+		restore()
+	}()
+
+	buf := make([]byte, 1024)
+	for {
+		tui.width, tui.height = getTerminalSize()
+		tui.Render(out)
+		n, err := os.Stdin.Read(buf)
+		if err != nil {
+			return
+		}
+		for _, key := range parseKeys(buf[:n]) {
+			if tui.HandleKey(key) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package core
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestTui(path string) *Tui {
+	uasset := &Uasset{Uexp: &Uexp{Lang: "US", Entries: []Entry{
+		{Id: "A", Text: "Hi\r\nCloud", SubEntries: []SubEntry{{Id: "ACTOR", Text: "Cloud"}}},
+		{Id: "B", Text: "Tifa"},
+	}}}
+	tui := &Tui{uasset: uasset, path: path, orig: copyEntries(uasset.Uexp.Entries), width: 80, height: 24}
+	tui.updateFilter()
+	return tui
+}
+
+// Send key input to the editor. It returns true when the editor quits.
+func sendKeys(tui *Tui, input string) bool {
+	for _, key := range parseKeys([]byte(input)) {
+		if tui.HandleKey(key) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestParseKeys(t *testing.T) {
+	keys := parseKeys([]byte("\x1b[Aaあ\r\x1b\x7f\x1b[3~\x01"))
+	want := []tuiKey{{name: "up"}, {r: 'a'}, {r: 'あ'}, {name: "enter"}, {name: "esc"}, {name: "backspace"}, {name: "delete"}}
+	if !slices.Equal(keys, want) {
+		t.Errorf("parseKeys: got %v, want %v", keys, want)
+	}
+}
+
+func TestTuiEditAndUndo(t *testing.T) {
+	tui := newTestTui("Story_TxtRes.uasset")
+	entries := tui.uasset.Uexp.Entries
+
+	// Line breaks are edited as <br>
+	sendKeys(tui, "\r")
+	if tui.mode != TUI_EDIT || string(tui.fields[0].value) != "Hi<br>Cloud" {
+		t.Fatalf("startEdit: got %q", string(tui.fields[0].value))
+	}
+	sendKeys(tui, strings.Repeat("\x7f", 5)+"Tifa\t!\r")
+	if entries[0].Text != "Hi\r\nTifa" || entries[0].SubEntries[0].Text != "Cloud!" || !tui.IsModified() {
+		t.Errorf("applyEdit: got %+v", entries[0])
+	}
+	sendKeys(tui, "\r\t\x7f\r")
+	if entries[0].SubEntries[0].Text != "Cloud" {
+		t.Errorf("applyEdit: got %+v", entries[0])
+	}
+
+	// No changes are not pushed to the undo stack
+	sendKeys(tui, "\r\r")
+	if len(tui.undo) != 2 || tui.message != "No changes." {
+		t.Errorf("applyEdit: got %d undo, %q", len(tui.undo), tui.message)
+	}
+
+	// Edits are undone in reverse order
+	sendKeys(tui, "u")
+	if entries[0].Text != "Hi\r\nTifa" || entries[0].SubEntries[0].Text != "Cloud!" {
+		t.Errorf("undoEdit: got %+v", entries[0])
+	}
+	sendKeys(tui, "u")
+	if entries[0].Text != "Hi\r\nCloud" || entries[0].SubEntries[0].Text != "Cloud" {
+		t.Errorf("undoEdit: got %+v", entries[0])
+	}
+	sendKeys(tui, "u")
+	if tui.message != "Nothing to undo." {
+		t.Errorf("undoEdit: got %q", tui.message)
+	}
+
+	// Broken markup is applied with a warning
+	sendKeys(tui, "\x1b[B\r<Red>\r")
+	if entries[1].Text != "Tifa<Red>" || !strings.HasPrefix(tui.message, "Warning: ") {
+		t.Errorf("applyEdit: got %q, %q", entries[1].Text, tui.message)
+	}
+
+	// Quitting with unsaved changes needs confirmation
+	if sendKeys(tui, "q") || !sendKeys(tui, "q") {
+		t.Errorf("q should quit after confirmation")
+	}
+}
+
+func TestTuiFilter(t *testing.T) {
+	tui := newTestTui("Story_TxtRes.uasset")
+	tests := []struct {
+		input   string
+		indices []int
+	}{
+		{"/tifa\r", []int{1}},
+		{"/\x7f\x7f\x7f\x7f\r", []int{0, 1}},
+		{"/A\r", []int{0, 1}},  // ids and texts are case-insensitive
+		{"/\x1b", []int{0, 1}}, // esc clears the filter
+		{"/<br>\r", []int{0}},  // line breaks are <br>
+	}
+	for _, test := range tests {
+		sendKeys(tui, test.input)
+		if !slices.Equal(tui.indices, test.indices) || tui.mode != TUI_LIST {
+			t.Errorf("filter %q: got %v, want %v", string(tui.filter), tui.indices, test.indices)
+		}
+	}
+
+	// The cursor stays in the filtered entries
+	sendKeys(tui, "/\x1b\x1b[B")
+	if tui.cursor != 1 {
+		t.Fatalf("cursor: got %d", tui.cursor)
+	}
+	sendKeys(tui, "/tifa\r")
+	if tui.cursor != 0 || tui.indices[tui.cursor] != 1 {
+		t.Errorf("cursor: got %d", tui.cursor)
+	}
+}
+
+func TestTuiSaveAsRefusesOriginal(t *testing.T) {
+	dir := t.TempDir()
+	orig := filepath.Join(dir, "Text", "Story_TxtRes.uasset")
+	if err := os.MkdirAll(filepath.Dir(orig), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range []string{orig, RemoveExtension(orig) + ".uexp"} {
+		if err := os.WriteFile(path, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	link := filepath.Join(dir, "Link")
+	if err := os.Symlink(filepath.Join(dir, "Text"), link); err != nil {
+		t.Fatal(err)
+	}
+	// .uexp of another asset that is the original .uexp
+	other := filepath.Join(dir, "Other", "Story_TxtRes.uasset")
+	if err := os.MkdirAll(filepath.Dir(other), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(RemoveExtension(orig)+".uexp", RemoveExtension(other)+".uexp"); err != nil {
+		t.Fatal(err)
+	}
+
+	tui := newTestTui(orig)
+	tests := []struct {
+		path string
+		err  string
+	}{
+		{orig, "can not overwrite"},
+		{filepath.Join(dir, "Text", ".", "Story_TxtRes.uasset"), "can not overwrite"},
+		{filepath.Join(link, "Story_TxtRes.uasset"), "can not overwrite"},
+		{other, "can not overwrite"},
+		{RemoveExtension(orig) + ".uexp", "not .uasset"},
+	}
+	for _, test := range tests {
+		if err := tui.SaveAs(test.path); err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("SaveAs(%s): got %v, want %q", test.path, err, test.err)
+		}
+	}
+	if tui.savePath != "" {
+		t.Errorf("savePath should not be changed: %s", tui.savePath)
+	}
+}
@@ -0,0 +1,587 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+)
+
+// Package flag for unversioned property serialization (PKG_UnversionedProperties)
+const PKG_UNVERSIONED_PROPERTIES = 0x2000
+
+// Structs serialized natively instead of unversioned properties
+var NATIVE_STRUCT_LAYOUTS = map[string][]string{
+	// Each item has "name:kind"
+	"Vector":           {"X:f32", "Y:f32", "Z:f32"},
+	"Vector2D":         {"X:f32", "Y:f32"},
+	"Vector4":          {"X:f32", "Y:f32", "Z:f32", "W:f32"},
+	"Rotator":          {"Pitch:f32", "Yaw:f32", "Roll:f32"},
+	"Quat":             {"X:f32", "Y:f32", "Z:f32", "W:f32"},
+	"LinearColor":      {"R:f32", "G:f32", "B:f32", "A:f32"},
+	"Color":            {"B:u8", "G:u8", "R:u8", "A:u8"},
+	"IntPoint":         {"X:i32", "Y:i32"},
+	"IntVector":        {"X:i32", "Y:i32", "Z:i32"},
+	"Box":              {"Min.X:f32", "Min.Y:f32", "Min.Z:f32", "Max.X:f32", "Max.Y:f32", "Max.Z:f32", "IsValid:u8"},
+	"Box2D":            {"Min.X:f32", "Min.Y:f32", "Max.X:f32", "Max.Y:f32", "IsValid:u8"},
+	"DateTime":         {"Ticks:i64"},
+	"Timespan":         {"Ticks:i64"},
+	"FrameNumber":      {"Value:i32"},
+	"PerPlatformFloat": {"Cooked:u8", "Value:f32"},
+	"PerPlatformInt":   {"Cooked:u8", "Value:i32"},
+}
+
+// Map that keeps the order of properties in json
+type PropertyMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func NewPropertyMap() *PropertyMap {
+	return &PropertyMap{values: map[string]interface{}{}}
+}
+
+func (m *PropertyMap) Set(key string, value interface{}) {
+	if _, ok := m.values[key]; !ok {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *PropertyMap) Get(key string) (interface{}, bool) {
+	value, ok := m.values[key]
+	return value, ok
+}
+
+func (m *PropertyMap) Keys() []string {
+	return m.keys
+}
+
+func (m *PropertyMap) MarshalJSON() ([]byte, error) {
+	buf := bytes.Buffer{}
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := JSONMarshal(key)
+		v, err := JSONMarshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// A fragment of an unversioned header
+type unversionedFragment struct {
+	skipNum   int
+	valueNum  int
+	hasZeroes bool
+}
+
+// Reader for unversioned properties of a package
+type PropertyReader struct {
+	pkg      *PackageAsset
+	mappings *Mappings
+}
+
+func NewPropertyReader(pkg *PackageAsset, mappings *Mappings) (*PropertyReader, error) {
+	if pkg.uasset.Ver == VER_FF7R {
+		return nil, fmt.Errorf("legacy packages (FF7R) use tagged properties, not unversioned properties")
+	}
+	if pkg.uasset.Summary.PkgFlags&PKG_UNVERSIONED_PROPERTIES == 0 {
+		return nil, fmt.Errorf("the package does not use unversioned properties")
+	}
+	return &PropertyReader{pkg: pkg, mappings: mappings}, nil
+}
+
+// Read an unversioned header. It returns schema indices of the properties,
+// and flags that are true when the value is zero and not serialized.
+func readUnversionedHeader(r *byteReader) ([]int, []bool) {
+	fragments := []unversionedFragment{}
+	zeroMaskNum := 0
+	for {
+		packed := r.uint16()
+		f := unversionedFragment{
+			skipNum:   int(packed & 0x7F),
+			hasZeroes: packed&0x80 != 0,
+			valueNum:  int(packed >> 9),
+		}
+		fragments = append(fragments, f)
+		if f.hasZeroes {
+			zeroMaskNum += f.valueNum
+		}
+		if packed&0x100 != 0 || r.err != nil {
+			break
+		}
+	}
+
+	zeroMask := make([]bool, zeroMaskNum)
+	if zeroMaskNum > 0 {
+		var words []uint32
+		if zeroMaskNum <= 8 {
+			words = []uint32{uint32(r.uint8())}
+		} else if zeroMaskNum <= 16 {
+			words = []uint32{uint32(r.uint16())}
+		} else {
+			for range (zeroMaskNum + 31) / 32 {
+				words = append(words, r.uint32())
+			}
+		}
+		for i := range zeroMaskNum {
+			zeroMask[i] = words[i/32]&(1<<(i%32)) != 0
+		}
+	}
+
+	indices, zeroes := []int{}, []bool{}
+	index, maskIndex := 0, 0
+	for _, f := range fragments {
+		index += f.skipNum
+		for range f.valueNum {
+			isZero := false
+			if f.hasZeroes {
+				isZero = zeroMask[maskIndex]
+				maskIndex++
+			}
+			indices = append(indices, index)
+			zeroes = append(zeroes, isZero)
+			index++
+		}
+	}
+	return indices, zeroes
+}
+
+// Read unversioned properties of a class or a struct
+func (p *PropertyReader) ReadProperties(r *byteReader, structName string) (*PropertyMap, error) {
+	props, err := p.mappings.getFlatProperties(structName)
+	if err != nil {
+		return nil, err
+	}
+	indices, zeroes := readUnversionedHeader(r)
+	if r.err != nil {
+		return nil, fmt.Errorf("failed to read unversioned header: %v", r.err)
+	}
+	values := NewPropertyMap()
+	for i, index := range indices {
+		if index >= len(props) || props[index] == nil {
+			return nil, fmt.Errorf("unknown schema index detected. (%s, %d)", structName, index)
+		}
+		prop := props[index]
+		var value interface{}
+		if zeroes[i] {
+			value, err = p.zeroValue(prop.typ)
+		} else {
+			value, err = p.readValue(r, prop.typ)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", structName, prop.name, err)
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", structName, prop.name, r.err)
+		}
+		values.Set(prop.name, value)
+	}
+	return values, nil
+}
+
+func (p *PropertyReader) readName(r *byteReader) string {
+	return p.pkg.readName(r)
+}
+
+func (p *PropertyReader) enumValue(enumName string, index int) string {
+	values, ok := p.mappings.Enums[enumName]
+	if !ok || index < 0 || index >= len(values) {
+		return fmt.Sprintf("%s::%d", enumName, index)
+	}
+	if strings.Contains(values[index], "::") {
+		return values[index]
+	}
+	return enumName + "::" + values[index]
+}
+
+func (p *PropertyReader) readText(r *byteReader) (interface{}, error) {
+	text := NewPropertyMap()
+	r.uint32() // Flags
+	history := int8(r.uint8())
+	switch history {
+	case -1:
+		// None
+		if r.int32() != 0 {
+			text.Set("CultureInvariantString", r.fstring())
+		}
+	case 0:
+		// Base
+		text.Set("Namespace", r.fstring())
+		text.Set("Key", r.fstring())
+		text.Set("SourceString", r.fstring())
+	default:
+		return nil, fmt.Errorf("unsupported text history: %d", history)
+	}
+	return text, nil
+}
+
+func (p *PropertyReader) readNativeStruct(r *byteReader, layout []string) interface{} {
+	values := NewPropertyMap()
+	for _, field := range layout {
+		name, kind, _ := strings.Cut(field, ":")
+		var value interface{}
+		switch kind {
+		case "f32":
+			value = jsonFloat(r.float32())
+		case "i32":
+			value = r.int32()
+		case "i64":
+			value = r.int64()
+		case "u8":
+			value = r.uint8()
+		}
+		values.Set(name, value)
+	}
+	return values
+}
+
+// Avoid errors with NaN and Inf in json
+func jsonFloat(f float32) interface{} {
+	if math.IsNaN(float64(f)) || math.IsInf(float64(f), 0) {
+		return fmt.Sprint(f)
+	}
+	return f
+}
+
+func (p *PropertyReader) readStruct(r *byteReader, structName string) (interface{}, error) {
+	if layout, ok := NATIVE_STRUCT_LAYOUTS[structName]; ok {
+		return p.readNativeStruct(r, layout), nil
+	}
+	switch structName {
+	case "Guid":
+		return fmt.Sprintf("%08X%08X%08X%08X", r.uint32(), r.uint32(), r.uint32(), r.uint32()), nil
+	case "SoftObjectPath", "SoftClassPath":
+		return p.readSoftObjectPath(r), nil
+	case "GameplayTagContainer":
+		tags := []string{}
+		count := r.int32()
+		for i := int32(0); i < count && r.err == nil; i++ {
+			tags = append(tags, p.readName(r))
+		}
+		return tags, nil
+	}
+	return p.ReadProperties(r, structName)
+}
+
+func (p *PropertyReader) readSoftObjectPath(r *byteReader) string {
+	path := p.readName(r)
+	if sub := r.fstring(); sub != "" {
+		path += ":" + sub
+	}
+	return path
+}
+
+// Read items of arrays, sets and maps
+func (p *PropertyReader) readItems(r *byteReader, t *PropertyType) ([]interface{}, error) {
+	count := int(r.int32())
+	if count < 0 || count > len(r.data)-r.offset {
+		return nil, fmt.Errorf("unexpected item count: %d", count)
+	}
+	items := make([]interface{}, 0, count)
+	for range count {
+		item, err := p.readValue(r, t)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		if r.err != nil {
+			break
+		}
+	}
+	return items, nil
+}
+
+func (p *PropertyReader) readValue(r *byteReader, t *PropertyType) (interface{}, error) {
+	switch t.Type {
+	case "BoolProperty":
+		return r.uint8() != 0, nil
+	case "ByteProperty":
+		value := r.uint8()
+		if t.EnumName != "" {
+			return p.enumValue(t.EnumName, int(value)), nil
+		}
+		return value, nil
+	case "Int8Property":
+		return int8(r.uint8()), nil
+	case "Int16Property":
+		return int16(r.uint16()), nil
+	case "UInt16Property":
+		return r.uint16(), nil
+	case "IntProperty":
+		return r.int32(), nil
+	case "UInt32Property":
+		return r.uint32(), nil
+	case "Int64Property":
+		return r.int64(), nil
+	case "UInt64Property":
+		return r.uint64(), nil
+	case "FloatProperty":
+		return jsonFloat(r.float32()), nil
+	case "DoubleProperty":
+		f := r.float64()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return fmt.Sprint(f), nil
+		}
+		return f, nil
+	case "EnumProperty":
+		inner := &PropertyType{Type: "ByteProperty"}
+		if t.Inner != nil {
+			inner = t.Inner
+		}
+		value, err := p.readValue(r, inner)
+		if err != nil {
+			return nil, err
+		}
+		var index int64
+		switch v := value.(type) {
+		case uint8:
+			index = int64(v)
+		case int8:
+			index = int64(v)
+		case int16:
+			index = int64(v)
+		case uint16:
+			index = int64(v)
+		case int32:
+			index = int64(v)
+		case uint32:
+			index = int64(v)
+		case int64:
+			index = v
+		default:
+			return value, nil
+		}
+		return p.enumValue(t.EnumName, int(index)), nil
+	case "NameProperty":
+		return p.readName(r), nil
+	case "StrProperty":
+		return r.fstring(), nil
+	case "TextProperty":
+		return p.readText(r)
+	case "ObjectProperty", "ClassProperty", "WeakObjectProperty", "InterfaceProperty":
+		return r.int32(), nil // FPackageIndex
+	case "LazyObjectProperty":
+		return fmt.Sprintf("%08X%08X%08X%08X", r.uint32(), r.uint32(), r.uint32(), r.uint32()), nil
+	case "SoftObjectProperty", "AssetObjectProperty", "SoftClassProperty":
+		return p.readSoftObjectPath(r), nil
+	case "DelegateProperty":
+		return map[string]interface{}{"object": r.int32(), "function": p.readName(r)}, nil
+	case "MulticastDelegateProperty":
+		delegates := []interface{}{}
+		count := r.int32()
+		for i := int32(0); i < count && r.err == nil; i++ {
+			delegates = append(delegates, map[string]interface{}{"object": r.int32(), "function": p.readName(r)})
+		}
+		return delegates, nil
+	case "FieldPathProperty":
+		path := []string{}
+		count := r.int32()
+		for i := int32(0); i < count && r.err == nil; i++ {
+			path = append(path, p.readName(r))
+		}
+		return map[string]interface{}{"path": path, "owner": r.int32()}, nil
+	case "StructProperty":
+		return p.readStruct(r, t.StructName)
+	case "ArrayProperty":
+		if t.Inner == nil {
+			return nil, fmt.Errorf("inner type of ArrayProperty not found")
+		}
+		return p.readItems(r, t.Inner)
+	case "SetProperty":
+		if t.Inner == nil {
+			return nil, fmt.Errorf("inner type of SetProperty not found")
+		}
+		if removed := r.int32(); removed != 0 {
+			return nil, fmt.Errorf("sets with removed items are not supported")
+		}
+		return p.readItems(r, t.Inner)
+	case "MapProperty":
+		if t.Inner == nil || t.Value == nil {
+			return nil, fmt.Errorf("key or value type of MapProperty not found")
+		}
+		if removed := r.int32(); removed != 0 {
+			return nil, fmt.Errorf("maps with removed items are not supported")
+		}
+		count := int(r.int32())
+		if count < 0 || count > len(r.data)-r.offset {
+			return nil, fmt.Errorf("unexpected item count: %d", count)
+		}
+		pairs := make([]interface{}, 0, count)
+		for range count {
+			key, err := p.readValue(r, t.Inner)
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.readValue(r, t.Value)
+			if err != nil {
+				return nil, err
+			}
+			pairs = append(pairs, map[string]interface{}{"key": key, "value": value})
+			if r.err != nil {
+				break
+			}
+		}
+		return pairs, nil
+	}
+	return nil, fmt.Errorf("unsupported property type: %s", t.Type)
+}
+
+// Get the value of a property that is zero and skipped in the data
+func (p *PropertyReader) zeroValue(t *PropertyType) (interface{}, error) {
+	r := &byteReader{data: make([]byte, 64)}
+	switch t.Type {
+	case "NameProperty":
+		return "None", nil
+	case "SoftObjectProperty", "AssetObjectProperty", "SoftClassProperty":
+		return "None", nil
+	case "ArrayProperty", "SetProperty", "MapProperty", "MulticastDelegateProperty":
+		return []interface{}{}, nil
+	case "StructProperty":
+		if _, ok := NATIVE_STRUCT_LAYOUTS[t.StructName]; ok || t.StructName == "Guid" {
+			return p.readStruct(r, t.StructName)
+		}
+		switch t.StructName {
+		case "SoftObjectPath", "SoftClassPath":
+			return "None", nil
+		case "GameplayTagContainer":
+			return []string{}, nil
+		}
+		props, err := p.mappings.getFlatProperties(t.StructName)
+		if err != nil {
+			return nil, err
+		}
+		values := NewPropertyMap()
+		for _, prop := range props {
+			if prop == nil {
+				continue
+			}
+			value, err := p.zeroValue(prop.typ)
+			if err != nil {
+				return nil, err
+			}
+			values.Set(prop.name, value)
+		}
+		return values, nil
+	case "DelegateProperty":
+		return map[string]interface{}{"object": 0, "function": "None"}, nil
+	case "FieldPathProperty":
+		return map[string]interface{}{"path": []string{}, "owner": 0}, nil
+	case "TextProperty":
+		return NewPropertyMap(), nil
+	}
+	return p.readValue(r, t)
+}
+
+// Properties of an export
+type ExportDump struct {
+	Name       string       `json:"name"`
+	Class      string       `json:"class"`
+	Properties *PropertyMap `json:"properties,omitempty"`
+	ExtraSize  int          `json:"extra_size"` // size of native data after properties
+	Error      string       `json:"error,omitempty"`
+}
+
+// Read properties of an export with its class
+func (pkg *PackageAsset) DumpExport(export *PackageExport) ExportDump {
+	dump := ExportDump{Name: export.Name, Class: export.Class}
+	if pkg.reader == nil {
+		dump.Error = "mappings are not set"
+		return dump
+	}
+	if export.Class == "" {
+		dump.Error = "class not resolved. use --class to set it"
+		return dump
+	}
+	r := &byteReader{data: pkg.Data[:export.Offset+export.Size], offset: export.Offset}
+	props, err := pkg.reader.ReadProperties(r, export.Class)
+	if err != nil {
+		dump.Error = err.Error()
+		return dump
+	}
+	dump.Properties = props
+	dump.ExtraSize = export.Offset + export.Size - r.offset
+	return dump
+}
+
+// Find the location of an unversioned property with a path (e.g. "LayoutData.Offsets.Right")
+func (p *PropertyReader) FindProperty(export *PackageExport, path string) (*PropertyValue, error) {
+	if export.Class == "" {
+		return nil, fmt.Errorf("class of %s not resolved. use --class to set it", export.Name)
+	}
+	keys := strings.Split(path, ".")
+	structName := export.Class
+	r := &byteReader{data: p.pkg.Data[:export.Offset+export.Size], offset: export.Offset}
+	for i := 0; i < len(keys); i++ {
+		props, err := p.mappings.getFlatProperties(structName)
+		if err != nil {
+			return nil, err
+		}
+		indices, zeroes := readUnversionedHeader(r)
+		var found *flatProperty
+		for j, index := range indices {
+			if index >= len(props) || props[index] == nil {
+				return nil, fmt.Errorf("unknown schema index detected. (%s, %d)", structName, index)
+			}
+			prop := props[index]
+			if prop.name == keys[i] {
+				if zeroes[j] {
+					return nil, fmt.Errorf("property has the zero value and is not serialized. (%s)", strings.Join(keys[:i+1], "."))
+				}
+				found = prop
+				break
+			}
+			if zeroes[j] {
+				continue
+			}
+			if _, err := p.readValue(r, prop.typ); err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", structName, prop.name, err)
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("failed to parse properties: %v", r.err)
+		}
+		if found == nil {
+			return nil, fmt.Errorf("property not found. (%s)", strings.Join(keys[:i+1], "."))
+		}
+
+		last := i == len(keys)-1
+		t := found.typ
+		switch t.Type {
+		case "FloatProperty", "IntProperty", "BoolProperty":
+			if !last {
+				break
+			}
+			return &PropertyValue{Type: t.Type, Offset: r.offset, Count: 1}, nil
+		case "StructProperty":
+			if components, ok := NATIVE_FLOAT_STRUCTS[t.StructName]; ok {
+				if last {
+					return &PropertyValue{Type: t.StructName, Offset: r.offset, Count: len(components)}, nil
+				}
+				j := slices.Index(components, keys[i+1])
+				if j < 0 || i+1 != len(keys)-1 {
+					return nil, fmt.Errorf("unknown component detected. (%s)", path)
+				}
+				return &PropertyValue{Type: "FloatProperty", Offset: r.offset + j*4, Count: 1}, nil
+			}
+			if _, ok := NATIVE_STRUCT_LAYOUTS[t.StructName]; ok || last {
+				break
+			}
+			// Search unversioned properties in the struct
+			structName = t.StructName
+			continue
+		}
+		return nil, fmt.Errorf("unsupported property type. (%s: %s %s)", strings.Join(keys[:i+1], "."), t.Type, t.StructName)
+	}
+	return nil, fmt.Errorf("property not found. (%s)", path)
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// Make a package that has a CanvasPanelSlot with unversioned properties.
+// LayoutData.Offsets.Left is zero and not serialized.
+func makeTestUnversionedPackage(t *testing.T) *PackageAsset {
+	floatType := PropertyType{Type: "FloatProperty"}
+	m := &Mappings{Schemas: map[string]*Schema{
+		"CanvasPanelSlot": {Properties: []SchemaProperty{
+			{Index: 0, Name: "LayoutData", Type: PropertyType{Type: "StructProperty", StructName: "AnchorData"}},
+		}},
+		"AnchorData": {Properties: []SchemaProperty{
+			{Index: 0, Name: "Offsets", Type: PropertyType{Type: "StructProperty", StructName: "Margin"}},
+			{Index: 1, Name: "Alignment", Type: PropertyType{Type: "StructProperty", StructName: "Vector2D"}},
+		}},
+		"Margin": {Properties: []SchemaProperty{
+			{Index: 0, Name: "Left", Type: floatType},
+			{Index: 1, Name: "Top", Type: floatType},
+			{Index: 2, Name: "Right", Type: floatType},
+			{Index: 3, Name: "Bottom", Type: floatType},
+		}},
+	}}
+	if err := m.init(); err != nil {
+		t.Fatal(err)
+	}
+
+	le := binary.LittleEndian
+	data := le.AppendUint16(nil, 1<<9|0x100)      // LayoutData (last fragment)
+	data = le.AppendUint16(data, 2<<9|0x100)      // Offsets, Alignment
+	data = le.AppendUint16(data, 4<<9|0x100|0x80) // Left, Top, Right, Bottom with a zero mask
+	data = append(data, 1)                        // zero mask (Left is zero)
+	for _, f := range []float32{10, 930, 210, 0.5, 1} {
+		data = le.AppendUint32(data, math.Float32bits(f))
+	}
+
+	pkg := &PackageAsset{
+		Data:    data,
+		Exports: []PackageExport{{Name: "CanvasPanelSlot_0", Offset: 0, Size: len(data)}},
+	}
+	pkg.reader = &PropertyReader{pkg: pkg, mappings: m}
+	pkg.SetExportClasses(map[string]string{"CanvasPanelSlot_0": "CanvasPanelSlot"})
+	return pkg
+}
+
+func TestFindUnversionedProperty(t *testing.T) {
+	pkg := makeTestUnversionedPackage(t)
+	export := &pkg.Exports[0]
+	tests := []struct {
+		path  string
+		value interface{} // nil means an error
+	}{
+		{"LayoutData.Offsets.Right", 930.0},
+		{"LayoutData.Offsets.Bottom", 210.0},
+		{"LayoutData.Alignment", []float64{0.5, 1}},
+		{"LayoutData.Alignment.Y", 1.0},
+		{"LayoutData.Offsets.Left", nil}, // not serialized
+		{"LayoutData.Offsets.Center", nil},
+		{"LayoutData.Alignment.Z", nil},
+		{"LayoutData", nil},
+	}
+	for _, test := range tests {
+		v, err := pkg.reader.FindProperty(export, test.path)
+		if test.value == nil {
+			if err == nil {
+				t.Errorf("FindProperty(%q) should return an error", test.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FindProperty(%q): %v", test.path, err)
+			continue
+		}
+		if value := pkg.GetValue(v); !patchValuesAreEqual(value, test.value) {
+			t.Errorf("FindProperty(%q): got %v, want %v", test.path, value, test.value)
+		}
+	}
+}
+
+func TestUnresolvedClass(t *testing.T) {
+	pkg := makeTestUnversionedPackage(t)
+	export := &pkg.Exports[0]
+	export.Class = ""
+	if _, err := pkg.reader.FindProperty(export, "LayoutData.Offsets.Right"); err == nil {
+		t.Errorf("unresolved class should return an error")
+	}
+	if dump := pkg.DumpExport(export); dump.Error == "" {
+		t.Errorf("unresolved class should be reported in the dump")
+	}
+}
@@ -0,0 +1,349 @@
+package core
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Tag of a serialized property
+type PropertyTag struct {
+	Name        string
+	Type        string
+	StructName  string // for StructProperty
+	Size        int
+	ArrayIndex  int
+	BoolOffset  int // offset of the value for BoolProperty
+	ValueOffset int
+}
+
+// Structs serialized as raw floats instead of tagged properties
+var NATIVE_FLOAT_STRUCTS = map[string][]string{
+	"Vector2D":    {"X", "Y"},
+	"Vector":      {"X", "Y", "Z"},
+	"Vector4":     {"X", "Y", "Z", "W"},
+	"LinearColor": {"R", "G", "B", "A"},
+}
+
+// Read a property tag. It returns nil at the end of properties ("None").
+func (pkg *PackageAsset) readPropertyTag(r *byteReader) *PropertyTag {
+	tag := &PropertyTag{}
+	tag.Name = pkg.readName(r)
+	if r.err != nil || tag.Name == "None" {
+		return nil
+	}
+	tag.Type = pkg.readName(r)
+	tag.Size = int(r.int32())
+	tag.ArrayIndex = int(r.int32())
+	switch tag.Type {
+	case "StructProperty":
+		tag.StructName = pkg.readName(r)
+		r.read(16) // StructGuid
+	case "BoolProperty":
+		tag.BoolOffset = r.offset
+		r.read(1)
+	case "ByteProperty", "EnumProperty":
+		pkg.readName(r) // EnumName
+	case "ArrayProperty", "SetProperty":
+		pkg.readName(r) // InnerType
+	case "MapProperty":
+		pkg.readName(r) // KeyType
+		pkg.readName(r) // ValueType
+	}
+	if r.uint8() != 0 {
+		r.read(16) // PropertyGuid
+	}
+	tag.ValueOffset = r.offset
+	r.offset += tag.Size
+	if r.err != nil {
+		return nil
+	}
+	return tag
+}
+
+// Split "Name[1]" into "Name" and 1
+func splitArrayIndex(key string) (string, int, error) {
+	name, index, found := strings.Cut(key, "[")
+	if !found {
+		return key, 0, nil
+	}
+	i, err := strconv.Atoi(strings.TrimSuffix(index, "]"))
+	if err != nil || !strings.HasSuffix(index, "]") {
+		return "", 0, fmt.Errorf("invalid array index. (%s)", key)
+	}
+	return name, i, nil
+}
+
+// Location of a value that can be patched
+type PropertyValue struct {
+	Type   string // FloatProperty, IntProperty, BoolProperty or a native struct name
+	Offset int
+	Count  int // number of floats for native structs
+}
+
+// Find a property with a path (e.g. "LayoutData.Offsets.Right", "ColorAndOpacity.A").
+// Unversioned properties (FF7R2) need mappings. (See SetMappings.)
+func (pkg *PackageAsset) FindProperty(export *PackageExport, path string) (*PropertyValue, error) {
+	if pkg.HasUnversionedProperties() {
+		if pkg.reader == nil {
+			return nil, fmt.Errorf("unversioned properties need mappings. use --mappings to set them")
+		}
+		return pkg.reader.FindProperty(export, path)
+	}
+	return pkg.findTaggedProperty(export, path)
+}
+
+// Find a tagged property (FF7R)
+func (pkg *PackageAsset) findTaggedProperty(export *PackageExport, path string) (*PropertyValue, error) {
+	keys := strings.Split(path, ".")
+	start, end := export.Offset, export.Offset+export.Size
+	for i := 0; i < len(keys); i++ {
+		name, arrayIndex, err := splitArrayIndex(keys[i])
+		if err != nil {
+			return nil, err
+		}
+		var found *PropertyTag
+		r := &byteReader{data: pkg.Data[:end], offset: start}
+		for r.offset < end {
+			tag := pkg.readPropertyTag(r)
+			if tag == nil {
+				break
+			}
+			if tag.Name == name && tag.ArrayIndex == arrayIndex {
+				found = tag
+				break
+			}
+		}
+		if r.err != nil {
+			return nil, fmt.Errorf("failed to parse properties: %v", r.err)
+		}
+		if found == nil {
+			return nil, fmt.Errorf("property not found. (%s)", strings.Join(keys[:i+1], "."))
+		}
+
+		last := i == len(keys)-1
+		switch found.Type {
+		case "FloatProperty", "IntProperty":
+			if !last || found.Size != 4 {
+				break
+			}
+			return &PropertyValue{Type: found.Type, Offset: found.ValueOffset, Count: 1}, nil
+		case "BoolProperty":
+			if !last {
+				break
+			}
+			return &PropertyValue{Type: found.Type, Offset: found.BoolOffset, Count: 1}, nil
+		case "StructProperty":
+			if components, ok := NATIVE_FLOAT_STRUCTS[found.StructName]; ok {
+				if found.Size != len(components)*4 {
+					break
+				}
+				if last {
+					return &PropertyValue{Type: found.StructName, Offset: found.ValueOffset, Count: len(components)}, nil
+				}
+				j := slices.Index(components, keys[i+1])
+				if j < 0 || i+1 != len(keys)-1 {
+					return nil, fmt.Errorf("unknown component detected. (%s)", path)
+				}
+				return &PropertyValue{Type: "FloatProperty", Offset: found.ValueOffset + j*4, Count: 1}, nil
+			}
+			if last {
+				break
+			}
+			// Search tagged properties in the struct
+			start, end = found.ValueOffset, found.ValueOffset+found.Size
+			continue
+		}
+		return nil, fmt.Errorf("unsupported property type. (%s: %s %s)", strings.Join(keys[:i+1], "."), found.Type, found.StructName)
+	}
+	return nil, fmt.Errorf("property not found. (%s)", path)
+}
+
+// Get the current value as float64, bool or []float64
+func (pkg *PackageAsset) GetValue(v *PropertyValue) interface{} {
+	le := binary.LittleEndian
+	switch v.Type {
+	case "FloatProperty":
+		return float64(math.Float32frombits(le.Uint32(pkg.Data[v.Offset:])))
+	case "IntProperty":
+		return float64(int32(le.Uint32(pkg.Data[v.Offset:])))
+	case "BoolProperty":
+		return pkg.Data[v.Offset] != 0
+	}
+	values := make([]float64, v.Count)
+	for i := range v.Count {
+		values[i] = float64(math.Float32frombits(le.Uint32(pkg.Data[v.Offset+i*4:])))
+	}
+	return values
+}
+
+// Set a value. value should have the same type as GetValue returns.
+func (pkg *PackageAsset) SetValue(v *PropertyValue, value interface{}) {
+	le := binary.LittleEndian
+	switch v.Type {
+	case "FloatProperty":
+		le.PutUint32(pkg.Data[v.Offset:], math.Float32bits(float32(value.(float64))))
+	case "IntProperty":
+		le.PutUint32(pkg.Data[v.Offset:], uint32(int32(value.(float64))))
+	case "BoolProperty":
+		pkg.Data[v.Offset] = 0
+		if value.(bool) {
+			pkg.Data[v.Offset] = 1
+		}
+	default:
+		for i, f := range value.([]float64) {
+			le.PutUint32(pkg.Data[v.Offset+i*4:], math.Float32bits(float32(f)))
+		}
+	}
+}
+
+// A change of a widget property
+type WidgetPatch struct {
+	Asset  string      `json:"asset,omitempty"` // file name without extension (e.g. Subtitle00). empty means all assets
+	Widget string      `json:"widget"`          // export name (e.g. CanvasPanelSlot_0)
+	Path   string      `json:"path"`            // property path (e.g. LayoutData.Offsets.Right)
+	Type   string      `json:"type"`            // FloatProperty, IntProperty, BoolProperty, Vector2D, Vector, Vector4 or LinearColor
+	Old    interface{} `json:"old"`
+	New    interface{} `json:"new"`
+}
+
+type WidgetPatchFile struct {
+	Patches []WidgetPatch `json:"patches"`
+}
+
+var WIDGET_PATCH_TYPES = []string{
+	"FloatProperty",
+	"IntProperty",
+	"BoolProperty",
+	"Vector2D",
+	"Vector",
+	"Vector4",
+	"LinearColor",
+}
+
+// Convert a json value to the type that GetValue returns
+func normalizePatchValue(value interface{}, patchType string) (interface{}, error) {
+	switch patchType {
+	case "FloatProperty", "IntProperty":
+		if f, ok := value.(float64); ok {
+			if patchType == "IntProperty" && f != math.Trunc(f) {
+				return nil, fmt.Errorf("%v is not an integer", f)
+			}
+			return f, nil
+		}
+	case "BoolProperty":
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+	default:
+		if list, ok := value.([]interface{}); ok && len(list) == len(NATIVE_FLOAT_STRUCTS[patchType]) {
+			values := make([]float64, 0, len(list))
+			for _, item := range list {
+				f, ok := item.(float64)
+				if !ok {
+					return nil, fmt.Errorf("%v is not a number", item)
+				}
+				values = append(values, f)
+			}
+			return values, nil
+		}
+	}
+	return nil, fmt.Errorf("unexpected value for %s (%v)", patchType, value)
+}
+
+// Load patches from json
+func LoadWidgetPatches(filePath string) []WidgetPatch {
+	Printf("Reading %s...\n", filePath)
+	jsonData, err := os.ReadFile(filePath)
+	if err != nil {
+		Throw(err)
+	}
+	patchFile := &WidgetPatchFile{}
+	if err := json.Unmarshal(jsonData, patchFile); err != nil {
+		Throw(err)
+	}
+	for i := range len(patchFile.Patches) {
+		p := &patchFile.Patches[i]
+		if p.Widget == "" || p.Path == "" {
+			Throw(fmt.Errorf("widget and path are required for patches. (patch %d)", i))
+		}
+		if !slices.Contains(WIDGET_PATCH_TYPES, p.Type) {
+			Throw(fmt.Errorf("unknown property type detected. (%s)", p.Type))
+		}
+		if p.Old == nil {
+			Throw(fmt.Errorf("old value is required for patches. (%s: %s)", p.Widget, p.Path))
+		}
+		if p.Old, err = normalizePatchValue(p.Old, p.Type); err != nil {
+			Throw(fmt.Errorf("%s: %s: %v", p.Widget, p.Path, err))
+		}
+		if p.New, err = normalizePatchValue(p.New, p.Type); err != nil {
+			Throw(fmt.Errorf("%s: %s: %v", p.Widget, p.Path, err))
+		}
+	}
+	return patchFile.Patches
+}
+
+func patchValuesAreEqual(a interface{}, b interface{}) bool {
+	switch a := a.(type) {
+	case float64:
+		return math.Abs(a-b.(float64)) <= 1e-4
+	case bool:
+		return a == b.(bool)
+	case []float64:
+		return slices.EqualFunc(a, b.([]float64), func(x float64, y float64) bool { return math.Abs(x-y) <= 1e-4 })
+	}
+	return false
+}
+
+// Check if the patch is for the asset (file name without extension)
+func (p *WidgetPatch) MatchAsset(assetName string) bool {
+	return p.Asset == "" || p.Asset == assetName
+}
+
+// Check if any patch is for the asset. Use it to skip assets before loading them.
+func HasWidgetPatches(patches []WidgetPatch, assetName string) bool {
+	for i := range len(patches) {
+		if patches[i].MatchAsset(assetName) {
+			return true
+		}
+	}
+	return false
+}
+
+// Apply patches for the asset. It returns the number of applied patches.
+// Patches without the asset name are skipped when the widget is not found.
+func (pkg *PackageAsset) ApplyPatches(patches []WidgetPatch, assetName string) int {
+	count := 0
+	for _, p := range patches {
+		if !p.MatchAsset(assetName) {
+			continue
+		}
+		export := pkg.FindExport(p.Widget)
+		if export == nil {
+			if p.Asset == "" {
+				continue
+			}
+			Throw(fmt.Errorf("widget not found. (%s)", p.Widget))
+		}
+		v, err := pkg.FindProperty(export, p.Path)
+		if err != nil {
+			Throw(fmt.Errorf("%s: %v", p.Widget, err))
+		}
+		if v.Type != p.Type {
+			Throw(fmt.Errorf("%s: %s: type mismatch (expected %s, got %s)", p.Widget, p.Path, p.Type, v.Type))
+		}
+		current := pkg.GetValue(v)
+		if !patchValuesAreEqual(current, p.Old) {
+			Throw(fmt.Errorf("%s: %s: old value mismatch (expected %v, got %v)", p.Widget, p.Path, p.Old, current))
+		}
+		Printf("  %s: %s: %v -> %v\n", p.Widget, p.Path, current, p.New)
+		pkg.SetValue(v, p.New)
+		count++
+	}
+	return count
+}
@@ -0,0 +1,188 @@
+package core
+
+import (
+	"encoding/binary"
+	"math"
+	"slices"
+	"testing"
+)
+
+// Writer of tagged properties (FF7R)
+type testPropertyWriter struct {
+	names *[]string
+	data  []byte
+}
+
+func (w *testPropertyWriter) sub() *testPropertyWriter {
+	return &testPropertyWriter{names: w.names}
+}
+
+func (w *testPropertyWriter) name(name string) {
+	index := slices.Index(*w.names, name)
+	if index < 0 {
+		index = len(*w.names)
+		*w.names = append(*w.names, name)
+	}
+	w.data = binary.LittleEndian.AppendUint32(w.data, uint32(index))
+	w.data = binary.LittleEndian.AppendUint32(w.data, 0)
+}
+
+func (w *testPropertyWriter) tag(name string, propType string, size int) {
+	w.name(name)
+	w.name(propType)
+	w.data = binary.LittleEndian.AppendUint32(w.data, uint32(size))
+	w.data = binary.LittleEndian.AppendUint32(w.data, 0) // ArrayIndex
+}
+
+func (w *testPropertyWriter) float(name string, value float32) {
+	w.tag(name, "FloatProperty", 4)
+	w.data = append(w.data, 0) // no PropertyGuid
+	w.data = binary.LittleEndian.AppendUint32(w.data, math.Float32bits(value))
+}
+
+func (w *testPropertyWriter) bool(name string, value bool) {
+	w.tag(name, "BoolProperty", 0)
+	if value {
+		w.data = append(w.data, 1, 0)
+	} else {
+		w.data = append(w.data, 0, 0)
+	}
+}
+
+func (w *testPropertyWriter) structure(name string, structName string, body []byte) {
+	w.tag(name, "StructProperty", len(body))
+	w.name(structName)
+	w.data = append(w.data, make([]byte, 16+1)...) // StructGuid and no PropertyGuid
+	w.data = append(w.data, body...)
+}
+
+// Make a package that has the subtitle widget of FF7R with tagged properties.
+func makeTestTaggedPackage() *PackageAsset {
+	names := []string{"None"}
+	w := &testPropertyWriter{names: &names}
+
+	offsets := w.sub()
+	offsets.float("Right", 930)
+	offsets.float("Bottom", 210)
+	offsets.name("None")
+	alignment := w.sub()
+	for _, f := range []float32{0.5, 1} {
+		alignment.data = binary.LittleEndian.AppendUint32(alignment.data, math.Float32bits(f))
+	}
+	layout := w.sub()
+	layout.structure("Offsets", "Margin", offsets.data)
+	layout.structure("Alignment", "Vector2D", alignment.data)
+	layout.name("None")
+	w.structure("LayoutData", "AnchorData", layout.data)
+	w.bool("bAutoSize", true)
+	w.name("None")
+	slotSize := len(w.data)
+
+	w.float("WrapTextAt", 930)
+	w.name("None")
+
+	pkg := &PackageAsset{
+		Data: w.data,
+		Exports: []PackageExport{
+			{Name: "CanvasPanelSlot_0", Offset: 0, Size: slotSize},
+			{Name: "TextBlock_0", Offset: slotSize, Size: len(w.data) - slotSize},
+		},
+	}
+	pkg.uasset.Names = names
+	return pkg
+}
+
+func TestFindTaggedProperty(t *testing.T) {
+	pkg := makeTestTaggedPackage()
+	export := pkg.FindExport("CanvasPanelSlot_0")
+	tests := []struct {
+		path  string
+		value interface{} // nil means an error
+	}{
+		{"LayoutData.Offsets.Right", 930.0},
+		{"LayoutData.Offsets.Bottom", 210.0},
+		{"LayoutData.Alignment", []float64{0.5, 1}},
+		{"LayoutData.Alignment.Y", 1.0},
+		{"bAutoSize", true},
+		{"LayoutData.Offsets.Left", nil},
+		{"LayoutData.Alignment.Z", nil},
+		{"LayoutData", nil},
+		{"bAutoSize[1]", nil},
+		{"bAutoSize[", nil},
+		{"WrapTextAt", nil}, // in another export
+	}
+	for _, test := range tests {
+		v, err := pkg.FindProperty(export, test.path)
+		if test.value == nil {
+			if err == nil {
+				t.Errorf("FindProperty(%q) should return an error", test.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("FindProperty(%q): %v", test.path, err)
+			continue
+		}
+		if value := pkg.GetValue(v); !patchValuesAreEqual(value, test.value) {
+			t.Errorf("FindProperty(%q): got %v, want %v", test.path, value, test.value)
+		}
+	}
+}
+
+func TestApplyPatches(t *testing.T) {
+	pkg := makeTestTaggedPackage()
+	patches := []WidgetPatch{
+		{Widget: "CanvasPanelSlot_0", Path: "LayoutData.Offsets.Right", Type: "FloatProperty", Old: 930.0, New: 1170.0},
+		{Widget: "CanvasPanelSlot_0", Path: "bAutoSize", Type: "BoolProperty", Old: true, New: false},
+		{Widget: "Missing_0", Path: "bAutoSize", Type: "BoolProperty", Old: true, New: false}, // skipped
+		{Asset: "Other", Widget: "Missing_0", Path: "bAutoSize", Type: "BoolProperty", Old: true, New: false},
+	}
+	if count := pkg.ApplyPatches(patches, "Subtitle00"); count != 2 {
+		t.Errorf("ApplyPatches: got %d, want 2", count)
+	}
+	v, _ := pkg.FindProperty(pkg.FindExport("CanvasPanelSlot_0"), "LayoutData.Offsets.Right")
+	if value := pkg.GetValue(v); value != 1170.0 {
+		t.Errorf("ApplyPatches: got %v", value)
+	}
+
+	errorPatches := []WidgetPatch{
+		// Old values should match the current values
+		{Widget: "CanvasPanelSlot_0", Path: "LayoutData.Offsets.Right", Type: "FloatProperty", Old: 930.0, New: 1170.0},
+		{Widget: "CanvasPanelSlot_0", Path: "LayoutData.Offsets.Bottom", Type: "IntProperty", Old: 210.0, New: 260.0},
+		{Asset: "Subtitle00", Widget: "Missing_0", Path: "bAutoSize", Type: "BoolProperty", Old: true, New: false},
+		{Widget: "CanvasPanelSlot_0", Path: "LayoutData.Offsets.Left", Type: "FloatProperty", Old: 0.0, New: 1.0},
+	}
+	for _, p := range errorPatches {
+		if err := Try(func() { pkg.ApplyPatches([]WidgetPatch{p}, "Subtitle00") }); err == nil {
+			t.Errorf("ApplyPatches(%s: %s) should throw an error", p.Widget, p.Path)
+		}
+	}
+}
+
+func TestResizeSubtitleWidget(t *testing.T) {
+	pkg := makeTestTaggedPackage()
+	pkg.ResizeSubtitleWidget("Subtitle00", 1170, 260)
+	tests := []struct {
+		widget string
+		path   string
+		value  float64
+	}{
+		{"CanvasPanelSlot_0", "LayoutData.Offsets.Right", 1170},
+		{"CanvasPanelSlot_0", "LayoutData.Offsets.Bottom", 260},
+		{"TextBlock_0", "WrapTextAt", 1170},
+	}
+	for _, test := range tests {
+		v, err := pkg.FindProperty(pkg.FindExport(test.widget), test.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value := pkg.GetValue(v); value != test.value {
+			t.Errorf("%s: %s: got %v, want %v", test.widget, test.path, value, test.value)
+		}
+	}
+
+	// The original size is not found in resized assets
+	if err := Try(func() { pkg.ResizeSubtitleWidget("Subtitle00", 1170, 260) }); err == nil {
+		t.Errorf("resized asset should be an unrecognized layout")
+	}
+}
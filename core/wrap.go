@@ -0,0 +1,411 @@
+package core
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Ranges of East Asian Wide (W) and Fullwidth (F) characters in EastAsianWidth.txt of Unicode 15.1.
+// Unassigned code points in CJK ideograph blocks are wide by default.
+var EAST_ASIAN_WIDE_RANGES = [][2]rune{
+	{0x1100, 0x115F}, // Hangul Jamo initials
+	{0x231A, 0x231B}, // Emoji and symbols (Emoji_Presentation)
+	{0x2329, 0x232A},
+	{0x23E9, 0x23EC},
+	{0x23F0, 0x23F0},
+	{0x23F3, 0x23F3},
+	{0x25FD, 0x25FE},
+	{0x2614, 0x2615},
+	{0x2648, 0x2653},
+	{0x267F, 0x267F},
+	{0x2693, 0x2693},
+	{0x26A1, 0x26A1},
+	{0x26AA, 0x26AB},
+	{0x26BD, 0x26BE},
+	{0x26C4, 0x26C5},
+	{0x26CE, 0x26CE},
+	{0x26D4, 0x26D4},
+	{0x26EA, 0x26EA},
+	{0x26F2, 0x26F3},
+	{0x26F5, 0x26F5},
+	{0x26FA, 0x26FA},
+	{0x26FD, 0x26FD},
+	{0x2705, 0x2705},
+	{0x270A, 0x270B},
+	{0x2728, 0x2728},
+	{0x274C, 0x274C},
+	{0x274E, 0x274E},
+	{0x2753, 0x2755},
+	{0x2757, 0x2757},
+	{0x2795, 0x2797},
+	{0x27B0, 0x27B0},
+	{0x27BF, 0x27BF},
+	{0x2B1B, 0x2B1C},
+	{0x2B50, 0x2B50},
+	{0x2B55, 0x2B55},
+	{0x2E80, 0x2E99}, // CJK radicals supplement
+	{0x2E9B, 0x2EF3},
+	{0x2F00, 0x2FD5}, // Kangxi radicals
+	{0x2FF0, 0x303E}, // Ideographic description characters, CJK symbols and punctuation
+	{0x3041, 0x3096}, // Hiragana
+	{0x3099, 0x30FF}, // Kana voiced sound marks, Katakana
+	{0x3105, 0x312F}, // Bopomofo
+	{0x3131, 0x318E}, // Hangul compatibility Jamo
+	{0x3190, 0x31E3}, // Kanbun, Bopomofo extended, CJK strokes
+	{0x31EF, 0x321E}, // Katakana phonetic extensions, enclosed CJK letters and months
+	{0x3220, 0x3247},
+	{0x3250, 0x4DBF}, // Enclosed CJK, CJK compatibility, CJK unified ideographs extension A
+	{0x4E00, 0xA48C}, // CJK unified ideographs, Yi syllables
+	{0xA490, 0xA4C6}, // Yi radicals
+	{0xA960, 0xA97C}, // Hangul Jamo extended-A
+	{0xAC00, 0xD7A3}, // Hangul syllables
+	{0xF900, 0xFAFF}, // CJK compatibility ideographs
+	{0xFE10, 0xFE19}, // Vertical forms
+	{0xFE30, 0xFE52}, // CJK compatibility forms, small form variants
+	{0xFE54, 0xFE66},
+	{0xFE68, 0xFE6B},
+	{0xFF01, 0xFF60},   // Fullwidth forms
+	{0xFFE0, 0xFFE6},   // Fullwidth signs
+	{0x16FE0, 0x16FE4}, // Ideographic symbols and punctuation
+	{0x16FF0, 0x16FF1},
+	{0x17000, 0x187F7}, // Tangut
+	{0x18800, 0x18CD5}, // Tangut components, Khitan small script
+	{0x18D00, 0x18D08}, // Tangut supplement
+	{0x1AFF0, 0x1AFF3}, // Kana extended-B
+	{0x1AFF5, 0x1AFFB},
+	{0x1AFFD, 0x1AFFE},
+	{0x1B000, 0x1B122}, // Kana supplement, Kana extended-A
+	{0x1B132, 0x1B132}, // Small kana extension
+	{0x1B150, 0x1B152},
+	{0x1B155, 0x1B155},
+	{0x1B164, 0x1B167},
+	{0x1B170, 0x1B2FB}, // Nushu
+	{0x1F004, 0x1F004}, // Emoji and symbols
+	{0x1F0CF, 0x1F0CF},
+	{0x1F18E, 0x1F18E},
+	{0x1F191, 0x1F19A},
+	{0x1F200, 0x1F202}, // Enclosed ideographic supplement
+	{0x1F210, 0x1F23B},
+	{0x1F240, 0x1F248},
+	{0x1F250, 0x1F251},
+	{0x1F260, 0x1F265},
+	{0x1F300, 0x1F320}, // Miscellaneous symbols and pictographs, emoticons
+	{0x1F32D, 0x1F335},
+	{0x1F337, 0x1F37C},
+	{0x1F37E, 0x1F393},
+	{0x1F3A0, 0x1F3CA},
+	{0x1F3CF, 0x1F3D3},
+	{0x1F3E0, 0x1F3F0},
+	{0x1F3F4, 0x1F3F4},
+	{0x1F3F8, 0x1F43E},
+	{0x1F440, 0x1F440},
+	{0x1F442, 0x1F4FC},
+	{0x1F4FF, 0x1F53D},
+	{0x1F54B, 0x1F54E},
+	{0x1F550, 0x1F567},
+	{0x1F57A, 0x1F57A},
+	{0x1F595, 0x1F596},
+	{0x1F5A4, 0x1F5A4},
+	{0x1F5FB, 0x1F64F},
+	{0x1F680, 0x1F6C5}, // Transport and map symbols
+	{0x1F6CC, 0x1F6CC},
+	{0x1F6D0, 0x1F6D2},
+	{0x1F6D5, 0x1F6D7},
+	{0x1F6DC, 0x1F6DF},
+	{0x1F6EB, 0x1F6EC},
+	{0x1F6F4, 0x1F6FC},
+	{0x1F7E0, 0x1F7EB}, // Geometric shapes extended
+	{0x1F7F0, 0x1F7F0},
+	{0x1F90C, 0x1F93A}, // Supplemental symbols and pictographs
+	{0x1F93C, 0x1F945},
+	{0x1F947, 0x1F9FF},
+	{0x1FA70, 0x1FA7C}, // Symbols and pictographs extended-A
+	{0x1FA80, 0x1FA88},
+	{0x1FA90, 0x1FABD},
+	{0x1FABF, 0x1FAC5},
+	{0x1FACE, 0x1FADB},
+	{0x1FAE0, 0x1FAE8},
+	{0x1FAF0, 0x1FAF8},
+	{0x20000, 0x2FFFD}, // CJK unified ideographs extension B-F, CJK compatibility ideographs supplement
+	{0x30000, 0x3FFFD}, // CJK unified ideographs extension G-H
+}
+
+// Get the display width of a character.
+// Wide and fullwidth characters are 2. Combining marks and format characters are 0.
+func RuneWidth(r rune) int {
+	if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+		return 0
+	}
+	// Find the first range that ends at r or later
+	i := sort.Search(len(EAST_ASIAN_WIDE_RANGES), func(i int) bool {
+		return EAST_ASIAN_WIDE_RANGES[i][1] >= r
+	})
+	if i < len(EAST_ASIAN_WIDE_RANGES) && EAST_ASIAN_WIDE_RANGES[i][0] <= r {
+		return 2
+	}
+	return 1
+}
+
+// Function that measures the width of a line. Tags should have no width.
+type WidthFunc func(line string) int
+
+// Get the display width of a line. Tags have no width.
+func TextWidth(text string) int {
+	width := 0
+	for _, t := range Tokenize(text) {
+		if t.Kind == TOKEN_TAG || t.Kind == TOKEN_CLOSE_TAG || t.Kind == TOKEN_LINE_BREAK {
+			continue
+		}
+		for _, r := range t.Raw {
+			width += RuneWidth(r)
+		}
+	}
+	return width
+}
+
+// Characters that should not start a line (kinsoku)
+var KINSOKU_NOT_START = "" +
+	"、。，．・：；？！゛゜ヽヾゝゞ々〻ー‐゠–〜～" +
+	"）〕］｝〉》」』】〙〗〟’”｠»" +
+	"ぁぃぅぇぉっゃゅょゎゕゖァィゥェォッャュョヮヵヶㇰㇱㇲㇳㇴㇵㇶㇷㇸㇹㇺㇻㇼㇽㇾㇿ" +
+	"‼⁇⁈⁉･" +
+	")]}>,.!?:;%" +
+	"︰︱︲︳︴︵︶︷︸︹︺︻︼︽︾︿﹀﹁﹂﹃﹄"
+
+// Characters that should not end a line (kinsoku)
+var KINSOKU_NOT_END = "" +
+	"（〔［｛〈《「『【〘〖〝‘“｟«" +
+	"([{<" +
+	"＄￥＃"
+
+type wrapSegment struct {
+	text    string
+	width   int
+	isSpace bool
+	isWide  bool
+}
+
+func isWrapSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '　'
+}
+
+// Split a line into segments.
+// A word of narrow characters is a segment. Each wide character is a segment.
+// Opening tags are attached to the next segment and closing tags are attached to the previous one.
+func splitWrapSegments(line string, measure WidthFunc) []wrapSegment {
+	segments := []wrapSegment{}
+	prefix := "" // opening tags waiting for the next segment
+	inWord := false
+	add := func(s wrapSegment) {
+		if !s.isSpace {
+			// Spaces can be dropped at line breaks. Tags should not be attached to them.
+			s.text = prefix + s.text
+			prefix = ""
+		}
+		segments = append(segments, s)
+	}
+	for _, t := range Tokenize(line) {
+		switch t.Kind {
+		case TOKEN_TAG:
+			prefix += t.Raw
+			inWord = false
+		case TOKEN_CLOSE_TAG:
+			if len(segments) > 0 && prefix == "" && !segments[len(segments)-1].isSpace {
+				segments[len(segments)-1].text += t.Raw
+			} else {
+				prefix += t.Raw
+			}
+		case TOKEN_PLACEHOLDER:
+			if inWord && prefix == "" {
+				segments[len(segments)-1].text += t.Raw
+			} else {
+				add(wrapSegment{text: t.Raw})
+				inWord = true
+			}
+		default:
+			for _, r := range t.Raw {
+				if isWrapSpace(r) {
+					add(wrapSegment{text: string(r), isSpace: true})
+					inWord = false
+				} else if RuneWidth(r) == 2 {
+					add(wrapSegment{text: string(r), isWide: true})
+					inWord = false
+				} else if inWord && prefix == "" {
+					segments[len(segments)-1].text += string(r)
+				} else {
+					add(wrapSegment{text: string(r)})
+					inWord = true
+				}
+			}
+		}
+	}
+	if prefix != "" {
+		segments = append(segments, wrapSegment{text: prefix})
+	}
+	for i := range len(segments) {
+		segments[i].width = measure(segments[i].text)
+	}
+	return segments
+}
+
+// Get the first visible character of a segment (tags are skipped)
+func firstVisibleRune(text string) rune {
+	for _, t := range Tokenize(text) {
+		if t.Kind == TOKEN_TEXT || t.Kind == TOKEN_MALFORMED {
+			for _, r := range t.Raw {
+				return r
+			}
+		} else if t.Kind == TOKEN_PLACEHOLDER {
+			return '{'
+		}
+	}
+	return 0
+}
+
+// Get the last visible character of a segment (tags are skipped)
+func lastVisibleRune(text string) rune {
+	tokens := Tokenize(text)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		t := tokens[i]
+		if t.Kind == TOKEN_TEXT || t.Kind == TOKEN_MALFORMED {
+			runes := []rune(t.Raw)
+			if len(runes) > 0 {
+				return runes[len(runes)-1]
+			}
+		} else if t.Kind == TOKEN_PLACEHOLDER {
+			return '}'
+		}
+	}
+	return 0
+}
+
+// Check if a line can break between two adjacent segments
+func canBreakBetween(prev *wrapSegment, next *wrapSegment) bool {
+	if prev.isSpace || next.isSpace {
+		return true
+	}
+	if !prev.isWide && !next.isWide {
+		// Latin words without spaces (e.g. "word" and "<Red>word")
+		return false
+	}
+	if strings.ContainsRune(KINSOKU_NOT_START, firstVisibleRune(next.text)) {
+		return false
+	}
+	if strings.ContainsRune(KINSOKU_NOT_END, lastVisibleRune(prev.text)) {
+		return false
+	}
+	return true
+}
+
+// Wrap a line without line breaks
+func wrapLine(line string, width int, measure WidthFunc) []string {
+	segments := splitWrapSegments(line, measure)
+
+	// Group segments that can not be split.
+	// Spaces are kept as separators between chunks.
+	type chunk struct {
+		sep   string // spaces before the chunk
+		text  string
+		width int
+		sepW  int
+	}
+	chunks := []chunk{}
+	sep, sepW := "", 0
+	for i := range len(segments) {
+		s := &segments[i]
+		if s.isSpace {
+			sep += s.text
+			sepW += s.width
+			continue
+		}
+		if len(chunks) > 0 && sep == "" && (s.width == 0 || !canBreakBetween(&segments[i-1], s)) {
+			last := &chunks[len(chunks)-1]
+			last.text += s.text
+			last.width += s.width
+			continue
+		}
+		chunks = append(chunks, chunk{sep: sep, text: s.text, width: s.width, sepW: sepW})
+		sep, sepW = "", 0
+	}
+
+	lines := []string{}
+	current := strings.Builder{}
+	currentW := 0
+	for i, c := range chunks {
+		if i > 0 && currentW > 0 && c.width > 0 && currentW+c.sepW+c.width > width {
+			lines = append(lines, current.String())
+			current.Reset()
+			currentW = 0
+		} else {
+			current.WriteString(c.sep)
+			currentW += c.sepW
+		}
+		current.WriteString(c.text)
+		currentW += c.width
+	}
+	current.WriteString(sep) // trailing spaces
+	lines = append(lines, current.String())
+	return lines
+}
+
+// Wrap lines wider than width.
+// Existing line breaks are kept. CJK text can break between characters except kinsoku positions,
+// and Latin text breaks at spaces.
+func WrapText(text string, width int) string {
+	return WrapTextWith(text, width, TextWidth)
+}
+
+// Wrap lines with a measure function (e.g. pixel width of a font)
+func WrapTextWith(text string, width int, measure WidthFunc) string {
+	if width <= 0 {
+		return text
+	}
+	lines := []string{}
+	for _, line := range strings.Split(text, "\r\n") {
+		if measure(line) <= width {
+			lines = append(lines, line)
+			continue
+		}
+		lines = append(lines, wrapLine(line, width, measure)...)
+	}
+	return strings.Join(lines, "\r\n")
+}
+
+// Wrap lines of the text wider than width.
+// Widths are measured with TextWidth when measure is nil.
+func (e *Entry) Wrap(width int, measure WidthFunc) {
+	if measure == nil {
+		measure = TextWidth
+	}
+	e.Text = WrapTextWith(e.Text, width, measure)
+}
+
+// Join all lines and wrap them again
+func (e *Entry) Rewrap(charWidth int, width int, measure WidthFunc) {
+	sep := " "
+	if charWidth == 2 {
+		// Asian languages use full width characters
+		sep = "　"
+	}
+	lines := strings.Split(e.Text, "\r\n")
+	e.Text = strings.Join(lines, sep)
+	e.Wrap(width, measure)
+}
+
+// Wrap subtitles wider than width. It returns the number of edited entries.
+func (uexp *Uexp) WrapSubtitles(width int, measure WidthFunc) int {
+	count := 0
+	for i := range len(uexp.Entries) {
+		e := &uexp.Entries[i]
+		if !e.IsSubtitle() || e.Text == e.Id {
+			continue
+		}
+		text := e.Text
+		e.Wrap(width, measure)
+		if text != e.Text {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,111 @@
+package core
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestRuneWidth(t *testing.T) {
+	tests := []struct {
+		r     rune
+		width int
+	}{
+		{'a', 1},
+		{'é', 1},
+		{'́', 0}, // combining acute accent
+		{'​', 0}, // zero width space
+		{'あ', 2},
+		{'漢', 2},
+		{'한', 2},
+		{'！', 2},
+		{'｡', 1},     // halfwidth katakana
+		{0x20B9F, 2}, // CJK extension B
+		{0x31350, 2}, // CJK extension H
+		{0xA960, 2},  // Hangul Jamo extended-A
+		{0x1160, 1},  // Hangul Jamo medial vowels
+		{'￥', 2},     // fullwidth yen sign
+		{'¥', 1},
+		{0x1F600, 2}, // emoji
+		{0x2600, 1},  // black sun with rays (text presentation)
+		{0x1B132, 2}, // hiragana letter small ko
+		{0x3FFFE, 1},
+	}
+	for _, test := range tests {
+		if width := RuneWidth(test.r); width != test.width {
+			t.Errorf("RuneWidth(%U): got %d, want %d", test.r, width, test.width)
+		}
+	}
+}
+
+func TestTextWidth(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+	}{
+		{"Cloud", 5},
+		{"<Red>Cloud</>", 5},
+		{"クラウド", 8},
+		{"{0}", 3},
+		{"a\r\nb", 2},
+	}
+	for _, test := range tests {
+		if width := TextWidth(test.text); width != test.width {
+			t.Errorf("TextWidth(%q): got %d, want %d", test.text, width, test.width)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  string
+	}{
+		// Latin text breaks at spaces
+		{"Let's go to the station.", 10, "Let's go\r\nto the\r\nstation."},
+		{"Let's go", 10, "Let's go"},
+		// Long words are not split
+		{"Supercalifragilistic word", 10, "Supercalifragilistic\r\nword"},
+		// Existing line breaks are kept
+		{"Hello\r\nworld and more", 9, "Hello\r\nworld and\r\nmore"},
+		// Tags have no width and stay with their words
+		{"<Red>Cloud</> and Tifa", 9, "<Red>Cloud</> and\r\nTifa"},
+		// CJK text breaks between characters
+		{"あいうえおかきくけこ", 10, "あいうえお\r\nかきくけこ"},
+		// Lines do not start with small kana or closing brackets
+		{"あいうえっと", 8, "あいう\r\nえっと"},
+		{"あいう」えお", 6, "あい\r\nう」え\r\nお"},
+		// Lines do not end with opening brackets
+		{"あい「うえお」", 6, "あい\r\n「うえ\r\nお」"},
+		// Width 0 disables wrapping
+		{"Let's go to the station.", 0, "Let's go to the station."},
+	}
+	for _, test := range tests {
+		if got := WrapText(test.text, test.width); got != test.want {
+			t.Errorf("WrapText(%q, %d): got %q, want %q", test.text, test.width, got, test.want)
+		}
+	}
+}
+
+func TestWrapTextWith(t *testing.T) {
+	// 10 pixels per rune
+	measure := func(line string) int {
+		return utf8.RuneCountInString(line) * 10
+	}
+	if got := WrapTextWith("aa bb cc", 50, measure); got != "aa bb\r\ncc" {
+		t.Errorf("WrapTextWith: got %q", got)
+	}
+}
+
+func TestRewrap(t *testing.T) {
+	e := &Entry{Text: "Let's go\r\nto the station."}
+	e.Rewrap(1, 20, nil)
+	if e.Text != "Let's go to the\r\nstation." {
+		t.Errorf("Rewrap: got %q", e.Text)
+	}
+	e = &Entry{Text: "あいう\r\nえお"}
+	e.Rewrap(2, 20, nil)
+	if e.Text != "あいう　えお" {
+		t.Errorf("Rewrap: got %q", e.Text)
+	}
+}
@@ -3,10 +3,13 @@ package main
 import (
 	"fmt"
 	"log"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -28,25 +31,154 @@ type options struct {
 	ignoreEmpty      bool
 	subtitleBoxWidth int
 	subttleBoxHeight int
+	tagCheck         string // off, warn or error
+	wrapWidth        int
+	tagCatalog       *core.TagCatalog
+	inputFormat      string // uasset, csv or json
+	lintConfig       string
+	reportFormat     string // text, json or sarif
+	lintReport       *core.LintReport
+	glossary         string
+	glossaryReport   *core.GlossaryReport
+	query            string
+	isRegex          bool
+	ignoreCase       bool
+	fields           string // comma separated list of text, id and sub
+	langs            string // comma separated list of languages
+	categories       string // comma separated list of id categories
+	searchQuery      *core.SearchQuery
+	searchResult     *core.SearchResult
+	index            string // path to index file
+	corpus           *core.Corpus
+	statsReport      *core.StatsReport
+	mtApi            string // libre or deepl
+	mtUrl            string
+	mtKey            string
+	mtSource         string // language code for translation api
+	mtTarget         string // language code for translation api
+	mtBatch          int
+	translator       core.Translator
+	pseudoExpansion  int
+	pseudoBrackets   bool
+	profile          string // path to dualsub profile
+	dualsubProfile   *core.DualsubProfile
+	order            string // comma separated languages for multisub mode
+	scriptDict       string // comma separated stages of OpenCC dictionaries
+	scriptOverride   string // path to override list
+	scriptConverter  *core.ScriptConverter
+	font             string // comma separated font files
+	glyphReport      *core.GlyphReport
+	patch            string // path to widget patch file
+	widgetPatches    []core.WidgetPatch
+	mappings         string // path to .usmap or .json
+	mappingsData     *core.Mappings
+	class            string // class for all exports, or comma separated export=class pairs
+	exportClasses    map[string]string
+	exportNames      string // comma separated export names to dump
+	charsetBase      string // path to a previous charset
+	charsetReport    *core.CharsetReport
+	fontSize         float64
+	measure          core.WidthFunc // measures lines in pixels with --font
+	watch            bool
+	watchInterval    int // milliseconds
+	host             string
+	port             int
+	progress         string // text or json
+	keepGoing        bool
+	failures         *core.FailureReport
+	sidecarDir       string
+	watchInitial     bool
 }
 
 var MODE_LIST = []string{
 	"export",
 	"import",
 	"dualsub",
+	"multisub",
+	"unmerge",
 	"resize",
+	"tags",
+	"lint",
+	"glossary",
+	"search",
+	"index",
+	"lookup",
+	"stats",
+	"mt",
+	"pseudo",
+	"convert-script",
+	"glyphs",
+	"charset",
+	"patch-widget",
+	"dump",
+	"serve",
+	"edit",
 	"test",
 }
 
+// Modes that do not use outdir for each asset
+var REPORT_MODES = []string{
+	"unmerge",
+	"tags",
+	"lint",
+	"glossary",
+	"search",
+	"index",
+	"stats",
+	"glyphs",
+	"charset",
+}
+
 var FORMAT_LIST = []string{
 	"csv",
 	"json",
 }
 
+var INPUT_FORMAT_LIST = []string{
+	"uasset",
+	"csv",
+	"json",
+}
+
+var REPORT_FORMAT_LIST = []string{
+	"text",
+	"json",
+	"sarif", // only for lint mode
+	"csv",   // only for stats mode
+	"html",  // only for stats mode
+}
+
+var PROGRESS_LIST = []string{
+	"text",
+	"json",
+}
+
+var TAG_CHECK_LIST = []string{
+	"off",
+	"warn",
+	"error",
+}
+
+// Load fonts to measure lines in pixels
+func loadMeasure(args *options) core.WidthFunc {
+	if args.fontSize <= 0 {
+		core.Throw("you should specify --font_size to measure lines with fonts.")
+	}
+	fonts := []string{}
+	for _, font := range core.SplitList(args.font) {
+		fonts = append(fonts, core.GetFullPath(font))
+	}
+	font := core.LoadFonts(fonts)
+	if !font.HasMetrics() {
+		core.Throw(fmt.Errorf("font has no metrics. (%s)", args.font))
+	}
+	return font.WidthFunc(args.fontSize)
+}
+
 // Parse arguments
 func argparse() *options {
 	args := &options{}
-	flag.StringVarP(&args.mode, "mode", "m", "export", "export or import is available")
+	flag.StringVarP(&args.mode, "mode", "m", "export", strings.Join(MODE_LIST, ", "))
 	flag.StringVarP(&args.format, "format", "f", "csv", "csv or json")
 	flag.StringVarP(&args.outdir, "outdir", "o", "out", "path to output directory")
 	flag.BoolVarP(&args.verbose, "verbose", "v", false, "shows more information")
@@ -54,8 +186,66 @@ func argparse() *options {
 	flag.IntVarP(&args.numWorkers, "num_workers", "n", 0, "number of worker processes. 0 means the number of CPUs")
 	flag.IntVar(&args.subtitleBoxWidth, "width", 930, "width of subtitle widget. the original width is 930")
 	flag.IntVar(&args.subttleBoxHeight, "height", 210, "height of subtitle widget. the original height is 210")
+	flag.StringVar(&args.tagCheck, "tag_check", "warn", "off, warn or error. checks if tags and placeholders are preserved when importing")
+	flag.IntVar(&args.wrapWidth, "wrap_width", 0, "wraps subtitle lines wider than this width when importing. full width characters are 2. 0 means no wrapping")
+	flag.StringVar(&args.inputFormat, "input_format", "uasset", "uasset, csv or json. file type to read in lint, search, stats, glyphs and charset modes")
+	flag.StringVar(&args.lintConfig, "lint_config", "", "path to a json file that configures lint rules")
+	flag.StringVar(&args.reportFormat, "report_format", "text", "text, json, sarif, csv or html. output format for lint, search, stats, glyphs and charset modes")
+	flag.StringVar(&args.reportFormat, "lint_format", "text", "old name of --report_format")
+	flag.CommandLine.MarkDeprecated("lint_format", "use --report_format instead")
+	flag.StringVar(&args.glossary, "glossary", "", "path to a csv file that has source terms and approved translations")
+	flag.StringVarP(&args.query, "query", "q", "", "text to search for in search mode")
+	flag.BoolVar(&args.isRegex, "regex", false, "uses query as a regular expression")
+	flag.BoolVar(&args.ignoreCase, "ignore_case", false, "ignores case when searching")
+	flag.StringVar(&args.fields, "fields", "text", "comma separated fields to search (text, id, sub)")
+	flag.StringVar(&args.langs, "langs", "", "comma separated languages to search (e.g. US,JP). empty means all")
+	flag.StringVar(&args.categories, "categories", "", "comma separated id categories to search (e.g. MAIN,QST_). empty means all")
+	flag.StringVar(&args.index, "index", "", "path to index file for index mode. the default is index.json in outdir")
+	flag.StringVar(&args.mtApi, "mt_api", "libre", "libre or deepl. translation api for mt mode")
+	flag.StringVar(&args.mtUrl, "mt_url", "", "endpoint of translation api (e.g. http://localhost:5000/translate)")
+	flag.StringVar(&args.mtKey, "mt_key", "", "api key for translation api")
+	flag.StringVar(&args.mtSource, "mt_source", "", "source language code (e.g. en). the default is detected from assets")
+	flag.StringVar(&args.mtTarget, "mt_target", "", "target language code (e.g. fr). the default is detected from assets")
+	flag.IntVar(&args.mtBatch, "mt_batch", 32, "number of texts sent in a request")
+	flag.IntVar(&args.pseudoExpansion, "pseudo_expansion", 30, "percentage of characters added to each line in pseudo mode")
+	flag.BoolVar(&args.pseudoBrackets, "pseudo_brackets", true, "wraps text with [ and ] in pseudo mode")
+	flag.StringVar(&args.profile, "profile", "", "path to a json file that configures dualsub and multisub modes")
+	flag.StringVar(&args.order, "order", "", "comma separated languages from top to bottom for multisub mode. the default is the order of paths")
+	flag.StringVar(&args.scriptDict, "script_dict", "", "comma separated OpenCC dictionaries for convert-script mode. use | to merge files into a stage (e.g. STPhrases.txt|STCharacters.txt,TWVariants.txt)")
+	flag.StringVar(&args.scriptOverride, "script_override", "", "path to a csv file that has source words and converted words. they have priority over dictionaries")
+	flag.StringVar(&args.font, "font", "", "comma separated font files (.ttf, .otf, .ttc or .ufont) for glyphs mode, or to measure lines in lint and import modes. glyphs of all fonts are merged")
+	flag.StringVar(&args.patch, "patch", "", "path to a json file that has widget properties to edit in patch-widget mode")
+	flag.StringVar(&args.mappings, "mappings", "", "path to a mappings file (.usmap or .json) for dump mode, or to locate unversioned properties of FF7R2 in patch-widget and resize modes")
+	flag.StringVar(&args.class, "class", "", "overrides classes of exports for dump, patch-widget and resize modes. use comma separated export=class pairs to set classes for each export. the default is resolved from the export map")
+	flag.StringVar(&args.exportNames, "export", "", "comma separated export names to dump. empty means all")
+	flag.Float64Var(&args.fontSize, "font_size", 0, "point size of the font. with --font, lint and import modes measure lines in pixels")
+	flag.BoolVar(&args.watch, "watch", false, "keeps running and processes files again when they are changed. only for import and dualsub modes")
+	flag.IntVar(&args.watchInterval, "watch_interval", 1000, "polling interval of --watch in milliseconds")
+	flag.BoolVar(&args.watchInitial, "watch_initial", false, "processes all files once before --watch starts watching. by default, only files changed after the start are processed")
+	flag.StringVar(&args.charsetBase, "charset_base", "", "previous output directory (or charset file) of charset mode to list new characters")
+	flag.StringVar(&args.host, "host", "127.0.0.1", "host name of the web editor for serve mode")
+	flag.IntVar(&args.port, "port", 8080, "port number of the web editor for serve mode")
+	flag.StringVar(&args.progress, "progress", "text", "text or json. json writes progress events as JSON lines to stdout, and other messages to stderr")
+	flag.BoolVar(&args.keepGoing, "keep-going", false, "keeps processing the remaining files when some files failed")
+	flag.BoolVar(&args.keepGoing, "keep_going", false, "same as --keep-going")
+	flag.CommandLine.MarkHidden("keep_going")
+	flag.StringVar(&args.sidecarDir, "sidecar_dir", "sidecars", "path to directory for *.dualsub.json files of dualsub and unmerge modes. it should not be in your mod packages")
 	flag.Parse()
 
+	if !slices.Contains(PROGRESS_LIST, args.progress) {
+		core.Throw(fmt.Errorf("unknown progress format detected (%s)", args.progress))
+	}
+	if args.progress == "json" {
+		if args.mode == "edit" {
+			core.Throw("--progress json is not available for edit mode.")
+		}
+		// Keep stdout for events only
+		core.EnableProgress(os.Stdout)
+		core.SetOutput(os.Stderr)
+	}
+
+	core.Printf("ff7r-text-tool v%s by Matyalatte\n", TOOL_VERSION)
+
 	// Check string options
 	if !slices.Contains(MODE_LIST, args.mode) {
 		core.Throw(fmt.Errorf("unknown mode detected (%s)", args.mode))
@@ -63,13 +253,31 @@ func argparse() *options {
 	if !slices.Contains(FORMAT_LIST, args.format) {
 		core.Throw(fmt.Errorf("unknown format detected (%s)", args.format))
 	}
+	if !slices.Contains(INPUT_FORMAT_LIST, args.inputFormat) {
+		core.Throw(fmt.Errorf("unknown input format detected (%s)", args.inputFormat))
+	}
+	if !slices.Contains(REPORT_FORMAT_LIST, args.reportFormat) {
+		core.Throw(fmt.Errorf("unknown report format detected (%s)", args.reportFormat))
+	}
+	if args.reportFormat == "sarif" && args.mode != "lint" {
+		core.Throw(fmt.Errorf("sarif is only available for lint mode (%s)", args.mode))
+	}
+	if (args.reportFormat == "csv" || args.reportFormat == "html") && args.mode != "stats" {
+		core.Throw(fmt.Errorf("%s is only available for stats mode (%s)", args.reportFormat, args.mode))
+	}
+	if !slices.Contains(TAG_CHECK_LIST, args.tagCheck) {
+		core.Throw(fmt.Errorf("unknown tag check detected (%s)", args.tagCheck))
+	}
+	if args.pseudoExpansion < 0 {
+		core.Throw(fmt.Errorf("invalid expansion (%d)", args.pseudoExpansion))
+	}
 
 	// Convert paths to absolute paths
 	rawFiles := flag.Args()
 	if len(rawFiles) == 0 {
 		core.Throw("you should specify a file path.")
 	}
-	if (args.mode == "import" || args.mode == "dualsub") && len(rawFiles) == 1 {
+	if (args.mode == "import" || args.mode == "dualsub" || args.mode == "multisub" || args.mode == "glossary" || args.mode == "mt") && len(rawFiles) == 1 {
 		core.Throw(fmt.Errorf("asset path is missing for this mode. (%s)", args.mode))
 	}
 	args.files = make([]string, 0, len(args.files))
@@ -81,16 +289,152 @@ func argparse() *options {
 		core.Throw(fmt.Errorf("you should specify Subtitle00.uasset for this mode. (%s)", args.files[0]))
 	}
 
+	if args.watch {
+		if args.mode != "import" && args.mode != "dualsub" {
+			core.Throw("--watch is only available for import and dualsub modes.")
+		}
+		if args.watchInterval <= 0 {
+			core.Throw(fmt.Errorf("invalid watch interval. (%d)", args.watchInterval))
+		}
+	} else if args.watchInitial {
+		core.Throw("--watch_initial is only available with --watch.")
+	}
+
+	if args.mode == "serve" && (args.port <= 0 || args.port > 65535) {
+		core.Throw(fmt.Errorf("invalid port number. (%d)", args.port))
+	}
+
 	args.outdir = core.MakeDir(args.outdir)
 
+	if args.mode == "tags" {
+		args.tagCatalog = core.NewTagCatalog()
+	} else if args.mode == "lint" {
+		config := core.LoadLintConfig(args.lintConfig)
+		if args.font != "" {
+			config.SetMeasure(loadMeasure(args), args.subtitleBoxWidth)
+		}
+		args.lintReport = core.NewLintReport(config)
+	} else if args.mode == "import" && args.font != "" {
+		args.measure = loadMeasure(args)
+	} else if args.mode == "glossary" {
+		if args.glossary == "" {
+			core.Throw("you should specify --glossary for glossary mode.")
+		}
+		terms := core.LoadGlossary(core.GetFullPath(args.glossary))
+		args.glossaryReport = core.NewGlossaryReport(terms)
+	} else if args.mode == "search" {
+		if args.query == "" {
+			core.Throw("you should specify a query for search mode.")
+		}
+		args.searchQuery = core.NewSearchQuery(args.query, args.isRegex, args.ignoreCase)
+		args.searchQuery.SetFields(core.SplitList(args.fields))
+		args.searchQuery.SetLangs(core.SplitList(args.langs))
+		args.searchQuery.SetCategories(core.SplitList(args.categories))
+		args.searchResult = core.NewSearchResult()
+	} else if args.mode == "index" {
+		if args.index == "" {
+			args.index = filepath.Join(args.outdir, "index.json")
+		}
+		args.corpus = core.LoadCorpus(args.index)
+		args.corpus.AddInput(args.files[0])
+	} else if args.mode == "stats" {
+		args.statsReport = core.NewStatsReport()
+	} else if args.mode == "dualsub" || args.mode == "multisub" {
+		profilePath := ""
+		if args.profile != "" {
+			profilePath = core.GetFullPath(args.profile)
+		}
+		args.dualsubProfile = core.LoadDualsubProfile(profilePath, args.subtitleBoxWidth)
+	} else if args.mode == "mt" {
+		if !slices.Contains(core.MT_API_LIST, args.mtApi) {
+			core.Throw(fmt.Errorf("unknown translation api detected (%s)", args.mtApi))
+		}
+		if args.mtUrl == "" {
+			core.Throw("you should specify --mt_url for mt mode.")
+		}
+		if args.mtBatch <= 0 {
+			core.Throw(fmt.Errorf("invalid batch size (%d)", args.mtBatch))
+		}
+		args.translator = core.NewTranslator(args.mtApi, args.mtUrl, args.mtKey)
+	} else if args.mode == "convert-script" {
+		if args.scriptDict == "" && args.scriptOverride == "" {
+			core.Throw("you should specify --script_dict for convert-script mode.")
+		}
+		stages := [][]string{}
+		for _, stage := range core.SplitList(args.scriptDict) {
+			files := []string{}
+			for _, file := range strings.Split(stage, "|") {
+				files = append(files, core.GetFullPath(file))
+			}
+			stages = append(stages, files)
+		}
+		overridePath := ""
+		if args.scriptOverride != "" {
+			overridePath = core.GetFullPath(args.scriptOverride)
+		}
+		args.scriptConverter = core.LoadScriptConverter(stages, overridePath)
+	} else if args.mode == "glyphs" {
+		fonts := []string{}
+		for _, font := range core.SplitList(args.font) {
+			fonts = append(fonts, core.GetFullPath(font))
+		}
+		if len(fonts) == 0 {
+			core.Throw("you should specify --font for glyphs mode.")
+		}
+		font := core.LoadFonts(fonts)
+		core.Printf("glyphs: %d\n", font.CountGlyphs())
+		langs := core.SplitList(args.langs)
+		for _, lang := range langs {
+			if !slices.Contains(core.LANG_LIST, lang) {
+				core.Throw(fmt.Errorf("unknown language detected (%s)", lang))
+			}
+		}
+		args.glyphReport = core.NewGlyphReport(font, langs)
+	} else if args.mode == "charset" {
+		langs := core.SplitList(args.langs)
+		for _, lang := range langs {
+			if !slices.Contains(core.LANG_LIST, lang) {
+				core.Throw(fmt.Errorf("unknown language detected (%s)", lang))
+			}
+		}
+		args.charsetReport = core.NewCharsetReport(langs)
+		if args.charsetBase != "" {
+			args.charsetBase = core.GetFullPath(args.charsetBase)
+		}
+	} else if args.mode == "patch-widget" || args.mode == "resize" || args.mode == "dump" {
+		if args.mode == "patch-widget" {
+			if args.patch == "" {
+				core.Throw("you should specify --patch for patch-widget mode.")
+			}
+			args.widgetPatches = core.LoadWidgetPatches(core.GetFullPath(args.patch))
+		}
+		if args.mode == "dump" && args.mappings == "" {
+			core.Throw("you should specify --mappings for dump mode.")
+		}
+		if args.mappings != "" {
+			args.mappingsData = core.LoadMappings(core.GetFullPath(args.mappings))
+		}
+		args.exportClasses = map[string]string{}
+		for _, item := range core.SplitList(args.class) {
+			export, class, found := strings.Cut(item, "=")
+			if !found {
+				export, class = "", item // class for all exports
+			}
+			args.exportClasses[export] = class
+		}
+	} else if args.mode == "lookup" && args.query == "" {
+		core.Throw("you should specify a query for lookup mode.")
+	}
+
 	// Get num workers
 	if args.numWorkers <= 0 {
 		args.numWorkers = runtime.NumCPU()
 	}
+	args.failures = core.NewFailureReport()
 
-	fmt.Printf("mode: %s\n", args.mode)
-	fmt.Printf("outdir: %s\n", args.outdir)
-	fmt.Printf("num_workers: %d\n", args.numWorkers)
+	core.Printf("mode: %s\n", args.mode)
+	core.Printf("outdir: %s\n", args.outdir)
+	core.Printf("num_workers: %d\n", args.numWorkers)
 	return args
 }
 
@@ -118,6 +462,7 @@ func Import(uassetPath string, newDataPath string, outPath string, args *options
 	// Read .uasset
 	uasset := core.Uasset{}
 	uasset.ReadFromFile(uassetPath)
+	orig := uasset.Uexp.Clone()
 
 	if args.format == "csv" {
 		// Read .csv
@@ -129,13 +474,309 @@ func Import(uassetPath string, newDataPath string, outPath string, args *options
 		uasset.Uexp.UpdateWithNewUexp(newUexp)
 	}
 
+	if args.wrapWidth > 0 {
+		uasset.Uexp.WrapSubtitles(args.wrapWidth, args.measure)
+	}
+
+	CheckMarkup(orig, uasset.Uexp, newDataPath, args)
+
 	// Save .uasset and .uexp
 	uasset.WriteToFile(outPath)
 
 	return 1
 }
 
-func Dualsub(firstPath string, secondPath string, outPath string, args *options) int {
+// Make sure that the new text keeps tags and placeholders in the original text
+func CheckMarkup(orig *core.Uexp, uexp *core.Uexp, newDataPath string, args *options) {
+	if args.tagCheck == "off" {
+		return
+	}
+	issues := uexp.CheckMarkup(orig)
+	if len(issues) == 0 {
+		return
+	}
+	msg := fmt.Sprintf("markup mismatch detected in %s", newDataPath)
+	for _, issue := range issues {
+		msg += "\n  " + issue.String()
+	}
+	if args.tagCheck == "error" {
+		core.Throw(msg)
+	}
+	core.Warn("%s", msg)
+}
+
+func CollectTags(uassetPath string, args *options) int {
+	uasset := core.Uasset{}
+	uasset.ReadFromFile(uassetPath)
+	args.tagCatalog.Add(uasset.Uexp)
+	return 1
+}
+
+func Lint(filePath string, rootDir string, args *options) int {
+	uexp := core.LoadUexpFromFile(filePath)
+	relPath, err := filepath.Rel(rootDir, filePath)
+	if err != nil {
+		core.Throw(err)
+	}
+	args.lintReport.Lint(uexp, relPath)
+	return 1
+}
+
+func SaveLintReport(args *options) {
+	report := args.lintReport
+	report.Sort()
+	if args.reportFormat == "text" {
+		report.WriteAsText(core.Output())
+	} else if args.reportFormat == "json" {
+		core.SaveAsJson(filepath.Join(args.outdir, "lint.json"), report.Issues)
+	} else {
+		sarif := report.ToSarif("ff7r-text-tool", TOOL_VERSION)
+		core.SaveAsJson(filepath.Join(args.outdir, "lint.sarif"), sarif)
+	}
+	errorCount := report.CountSeverity("error")
+	if errorCount > 0 {
+		core.Throw(fmt.Errorf("lint found %d errors", errorCount))
+	}
+}
+
+func Glossary(srcPath string, dstPath string, rootDir string, args *options) int {
+	src := core.Uasset{}
+	src.ReadFromFile(srcPath)
+	dst := core.Uasset{}
+	dst.ReadFromFile(dstPath)
+	relPath, err := filepath.Rel(rootDir, srcPath)
+	if err != nil {
+		core.Throw(err)
+	}
+	args.glossaryReport.Check(src.Uexp, dst.Uexp, relPath)
+	return 1
+}
+
+func SaveGlossaryReport(args *options) {
+	report := args.glossaryReport
+	report.CheckActors()
+	report.Sort()
+	report.WriteAsText(core.Output())
+	core.SaveAsJson(filepath.Join(args.outdir, "glossary.json"), report.Issues)
+}
+
+func Search(filePath string, rootDir string, args *options) int {
+	uexp := core.LoadUexpFromFile(filePath)
+	relPath, err := filepath.Rel(rootDir, filePath)
+	if err != nil {
+		core.Throw(err)
+	}
+	args.searchResult.Search(uexp, relPath, args.searchQuery)
+	return 1
+}
+
+func SaveSearchResult(args *options) {
+	result := args.searchResult
+	result.Sort()
+	if args.reportFormat == "json" {
+		core.SaveAsJson(filepath.Join(args.outdir, "search.json"), result.Hits)
+		return
+	}
+	// Highlight matches with colors when stdout is a terminal
+	highlightStart, highlightEnd := "[[", "]]"
+	if file, ok := core.Output().(*os.File); ok {
+		if info, err := file.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+			highlightStart, highlightEnd = "\x1b[1;31m", "\x1b[0m"
+		}
+	}
+	result.WriteAsText(core.Output(), highlightStart, highlightEnd)
+}
+
+func Index(filePath string, args *options) int {
+	if args.corpus.UpdateAsset(filePath, args.corpus.RelPath(filePath)) {
+		return 1
+	}
+	return 0
+}
+
+func SaveIndex(args *options) {
+	removed := args.corpus.RemoveMissingAssets(args.files[0])
+	if removed > 0 {
+		core.Printf("Removed %d assets from index\n", removed)
+	}
+	args.corpus.Rebuild()
+	args.corpus.Save(args.index)
+}
+
+// Answer a query from index file without reading assets
+func Lookup(indexPath string, args *options) int {
+	corpus := core.LoadCorpus(indexPath)
+	var hits []core.CorpusHit
+	if slices.Contains(core.SplitList(args.fields), "id") {
+		hits = corpus.FindId(args.query)
+	} else {
+		hits = corpus.FindText(args.query, core.SplitList(args.langs))
+	}
+
+	if args.reportFormat == "json" {
+		core.SaveAsJson(filepath.Join(args.outdir, "lookup.json"), hits)
+	} else {
+		for _, hit := range hits {
+			text := core.GoStrToCsvStr(hit.Entry.Text)
+			core.Printf("%s: [%s] %s: %s\n", hit.Path, hit.Lang, hit.Entry.Id, text)
+		}
+	}
+	return len(hits)
+}
+
+func Stats(filePath string, rootDir string, args *options) int {
+	uexp := core.LoadUexpFromFile(filePath)
+	relPath, err := filepath.Rel(rootDir, filePath)
+	if err != nil {
+		core.Throw(err)
+	}
+	args.statsReport.Add(uexp, filepath.ToSlash(filepath.Dir(relPath)))
+	return 1
+}
+
+func SaveStatsReport(args *options) {
+	outPath := filepath.Join(args.outdir, "stats."+args.reportFormat)
+	if args.reportFormat == "text" {
+		args.statsReport.WriteAsText(core.Output())
+	} else if args.reportFormat == "csv" {
+		args.statsReport.SaveAsCsv(outPath)
+	} else if args.reportFormat == "json" {
+		core.SaveAsJson(outPath, args.statsReport)
+	} else {
+		args.statsReport.SaveAsHtml(outPath)
+	}
+}
+
+func Glyphs(filePath string, rootDir string, args *options) int {
+	uexp := core.LoadUexpFromFile(filePath)
+	relPath, err := filepath.Rel(rootDir, filePath)
+	if err != nil {
+		core.Throw(err)
+	}
+	if args.glyphReport.Check(uexp, relPath) {
+		return 1
+	}
+	return 0
+}
+
+func SaveGlyphReport(args *options) {
+	report := args.glyphReport
+	if args.reportFormat == "text" {
+		report.WriteAsText(core.Output())
+	} else {
+		core.SaveAsJson(filepath.Join(args.outdir, "glyphs.json"), report.GetMissingGlyphs())
+	}
+	missing := len(report.GetMissingGlyphs())
+	if missing > 0 {
+		core.Throw(fmt.Errorf("font has no glyphs for %d characters", missing))
+	}
+}
+
+func Charset(filePath string, args *options) int {
+	uexp := core.LoadUexpFromFile(filePath)
+	if args.charsetReport.Add(uexp) {
+		return 1
+	}
+	return 0
+}
+
+func SaveCharset(args *options) {
+	report := args.charsetReport
+	var diffs []core.CharsetDiff
+	if args.charsetBase != "" {
+		// Compare before writing files since the base can be outdir
+		diffs = report.Diff(args.charsetBase)
+	}
+	report.SaveFiles(args.outdir)
+	report.WriteAsText(core.Output())
+	if args.charsetBase == "" {
+		return
+	}
+	if args.reportFormat == "text" {
+		core.WriteCharsetDiffAsText(core.Output(), diffs)
+	} else {
+		core.SaveAsJson(filepath.Join(args.outdir, "charset_diff.json"), diffs)
+	}
+}
+
+// Fill untranslated entries with machine translation
+func MachineTranslate(srcPath string, dstPath string, outPath string, args *options) int {
+	src := core.Uasset{}
+	src.ReadFromFile(srcPath)
+	dst := core.Uasset{}
+	dst.ReadFromFile(dstPath)
+
+	sourceLang := args.mtSource
+	if sourceLang == "" {
+		sourceLang = core.MT_LANG_CODES[src.Uexp.Lang]
+	}
+	targetLang := args.mtTarget
+	if targetLang == "" {
+		targetLang = core.MT_LANG_CODES[dst.Uexp.Lang]
+	}
+
+	count := core.MachineTranslate(src.Uexp, dst.Uexp, args.translator, sourceLang, targetLang, args.mtBatch)
+	if count == 0 {
+		return 0
+	}
+	if args.format == "csv" {
+		core.SaveAsCsv(outPath, dst.Uexp)
+	} else {
+		core.SaveAsJson(outPath, dst.Uexp)
+	}
+	return 1
+}
+
+// Rewrite text with accented characters to test text expansion and encoding
+func Pseudo(uassetPath string, outPath string, args *options) int {
+	uasset := core.Uasset{}
+	uasset.ReadFromFile(uassetPath)
+
+	opts := &core.PseudoOptions{
+		Expansion: args.pseudoExpansion,
+		Brackets:  args.pseudoBrackets,
+	}
+	if uasset.Uexp.Pseudolocalize(opts) == 0 {
+		return 0
+	}
+	uasset.WriteToFile(outPath)
+	return 1
+}
+
+// Convert texts between Simplified and Traditional Chinese.
+// It writes .uasset when the destination asset exists. Otherwise, it writes .csv or .json.
+func ConvertScript(srcPath string, dstPath string, outPath string, args *options) int {
+	src := core.Uasset{}
+	src.ReadFromFile(srcPath)
+
+	if args.ignoreEmpty && len(src.Uexp.Entries) == 0 {
+		return 0 // Do not export empty assets
+	}
+
+	if dstPath == "" {
+		dst := src.Uexp.Clone()
+		dst.Lang = core.SCRIPT_TARGETS[src.Uexp.Lang]
+		if args.scriptConverter.ConvertUexp(src.Uexp, dst) == 0 {
+			return 0
+		}
+		if args.format == "csv" {
+			core.SaveAsCsv(outPath+".csv", dst)
+		} else {
+			core.SaveAsJson(outPath+".json", dst)
+		}
+		return 1
+	}
+
+	dst := core.Uasset{}
+	dst.ReadFromFile(dstPath)
+	if args.scriptConverter.ConvertUexp(src.Uexp, dst.Uexp) == 0 {
+		return 0
+	}
+	dst.WriteToFile(outPath + ".uasset")
+	return 1
+}
+
+func Dualsub(firstPath string, secondPath string, outPath string, sidecarPath string, args *options) int {
 	// Read .uasset
 	uasset1 := core.Uasset{}
 	uasset1.ReadFromFile(firstPath)
@@ -147,17 +788,147 @@ func Dualsub(firstPath string, secondPath string, outPath string, args *options)
 	uasset2 := core.Uasset{}
 	uasset2.ReadFromFile(secondPath)
 
-	mergedCount := core.MakeDualsub(uasset1.Uexp, uasset2.Uexp)
+	mergedCount, sidecar := core.MakeDualsub(uasset1.Uexp, uasset2.Uexp, args.dualsubProfile)
 
 	if mergedCount == 0 {
 		return 0
 	}
 	// Save .uasset and .uexp
 	uasset1.WriteToFile(outPath)
+
+	// Save split points for unmerge mode
+	core.MakeDir(filepath.Dir(sidecarPath))
+	core.SaveAsJson(sidecarPath, sidecar)
+	return 1
+}
+
+// Rebuild texts of both languages from an asset made by dualsub mode
+func Unmerge(uassetPath string, sidecarPath string, relDir string, args *options) int {
+	if !core.PathExists(sidecarPath) {
+		return 0 // Not merged
+	}
+	sidecar := &core.DualsubSidecar{}
+	core.LoadFromJson(sidecarPath, sidecar)
+
+	uasset := core.Uasset{}
+	uasset.ReadFromFile(uassetPath)
+	uexp2 := core.Unmerge(uasset.Uexp, sidecar)
+
+	_, baseName, _ := core.SplitFilePath(uassetPath)
+	outdir1 := core.MakeDir(filepath.Join(args.outdir, sidecar.Lang1, relDir))
+	outdir2 := core.MakeDir(filepath.Join(args.outdir, sidecar.Lang2, relDir))
+
+	// The first language as .uasset and the second language as .csv or .json
+	uasset.WriteToFile(filepath.Join(outdir1, baseName+".uasset"))
+	outPath := filepath.Join(outdir2, baseName+"."+args.format)
+	if args.format == "csv" {
+		core.SaveAsCsv(outPath, uexp2)
+	} else {
+		core.SaveAsJson(outPath, uexp2)
+	}
+	return 1
+}
+
+// Stack subtitles of all languages into the first asset
+func Multisub(paths []string, outPath string, args *options) int {
+	uassets := make([]core.Uasset, len(paths))
+	uexps := make([]*core.Uexp, 0, len(paths))
+	for i, path := range paths {
+		uassets[i].ReadFromFile(path)
+		uexps = append(uexps, uassets[i].Uexp)
+	}
+
+	if args.ignoreEmpty && len(uexps[0].Entries) == 0 {
+		return 0 // Do not export empty assets
+	}
+
+	order := []int{}
+	langs := core.SplitList(args.order)
+	if len(langs) == 0 {
+		for i := range len(uexps) {
+			order = append(order, i)
+		}
+	}
+	for _, lang := range langs {
+		i := slices.IndexFunc(uexps, func(uexp *core.Uexp) bool { return uexp.Lang == lang })
+		if i < 0 {
+			core.Throw(fmt.Errorf("language not found in assets (%s)", lang))
+		}
+		order = append(order, i)
+	}
+
+	mergedCount := core.MakeMultisub(uexps, order, args.dualsubProfile)
+	if mergedCount == 0 {
+		return 0
+	}
+
+	// Save .uasset and .uexp
+	uassets[0].WriteToFile(outPath)
+	return 1
+}
+
+// Load a package with mappings and classes from options
+func LoadPackage(uassetPath string, args *options) *core.PackageAsset {
+	pkg := core.LoadPackageAsset(uassetPath)
+	if args.mappingsData != nil {
+		if err := pkg.SetMappings(args.mappingsData); err != nil {
+			core.Throw(fmt.Errorf("%v (%s)", err, uassetPath))
+		}
+	}
+	pkg.SetExportClasses(args.exportClasses)
+	return pkg
+}
+
+// Edit widget properties with a patch file
+func PatchWidget(uassetPath string, outPath string, args *options) int {
+	_, baseName, _ := core.SplitFilePath(uassetPath)
+	if !core.HasWidgetPatches(args.widgetPatches, baseName) {
+		return 0
+	}
+	widget := LoadPackage(uassetPath, args)
+	if widget.ApplyPatches(args.widgetPatches, baseName) == 0 {
+		return 0
+	}
+	widget.WriteToFile(outPath)
+	return 1
+}
+
+// Resize the subtitle widget
+func Resize(uassetPath string, outPath string, args *options) int {
+	widget := LoadPackage(uassetPath, args)
+	_, baseName, _ := core.SplitFilePath(uassetPath)
+	widget.ResizeSubtitleWidget(baseName, args.subtitleBoxWidth, args.subttleBoxHeight)
+	widget.WriteToFile(outPath)
+	return 1
+}
+
+// Dump properties of exports as json
+func Dump(uassetPath string, outPath string, args *options) int {
+	pkg := LoadPackage(uassetPath, args)
+	names := core.SplitList(args.exportNames)
+	dumps := []core.ExportDump{}
+	for i := range len(pkg.Exports) {
+		export := &pkg.Exports[i]
+		if len(names) > 0 && !slices.Contains(names, export.Name) {
+			continue
+		}
+		dump := pkg.DumpExport(export)
+		if dump.Error != "" {
+			core.Warn("failed to read properties of %s. %s", export.Name, dump.Error)
+		}
+		dumps = append(dumps, dump)
+	}
+	if len(dumps) == 0 {
+		return 0
+	}
+	core.SaveAsJson(outPath, dumps)
 	return 1
 }
 
 func processFile(filePath string, rootDir string, assetDir string, args *options) int {
+	start := time.Now()
+	core.EmitProgress(core.ProgressEvent{Event: "file-started", Path: filePath})
+
 	parentDir, baseName, _ := core.SplitFilePath(filePath)
 	relPath, err := filepath.Rel(rootDir, filePath)
 	if err != nil {
@@ -169,16 +940,51 @@ func processFile(filePath string, rootDir string, assetDir string, args *options
 	var secondPath string
 	if core.PathExists(assetDir) && core.PathIsDir(assetDir) {
 		_, rootBase := core.SplitPath(rootDir)
-		outdir = core.MakeDir(filepath.Join(args.outdir, rootBase, relPath))
+		outdir = filepath.Join(args.outdir, rootBase, relPath)
 		secondPath = filepath.Join(assetDir, relPath, baseName+".uasset")
 	} else {
-		outdir = core.MakeDir(filepath.Join(args.outdir, relPath))
+		outdir = filepath.Join(args.outdir, relPath)
 		secondPath = assetDir
 	}
+	if !slices.Contains(REPORT_MODES, args.mode) {
+		outdir = core.MakeDir(outdir)
+	}
 
 	processed := 0
 
-	if args.mode == "export" {
+	if args.mode == "tags" {
+		processed = CollectTags(filepath.Join(parentDir, baseName+".uasset"), args)
+	} else if args.mode == "lint" {
+		processed = Lint(filePath, rootDir, args)
+	} else if args.mode == "search" {
+		processed = Search(filePath, rootDir, args)
+	} else if args.mode == "index" {
+		processed = Index(filePath, args)
+	} else if args.mode == "stats" {
+		processed = Stats(filePath, rootDir, args)
+	} else if args.mode == "glyphs" {
+		processed = Glyphs(filePath, rootDir, args)
+	} else if args.mode == "charset" {
+		processed = Charset(filePath, args)
+	} else if args.mode == "pseudo" {
+		uassetPath := filepath.Join(parentDir, baseName+".uasset")
+		outPath := filepath.Join(outdir, baseName+".uasset")
+		processed = Pseudo(uassetPath, outPath, args)
+	} else if args.mode == "mt" {
+		srcPath := filepath.Join(parentDir, baseName+".uasset")
+		outPath := filepath.Join(outdir, baseName+"."+args.format)
+		processed = MachineTranslate(srcPath, secondPath, outPath, args)
+	} else if args.mode == "convert-script" {
+		srcPath := filepath.Join(parentDir, baseName+".uasset")
+		dstPath := ""
+		if len(args.files) > 1 {
+			dstPath = secondPath
+		}
+		processed = ConvertScript(srcPath, dstPath, filepath.Join(outdir, baseName), args)
+	} else if args.mode == "glossary" {
+		firstPath := filepath.Join(parentDir, baseName+".uasset")
+		processed = Glossary(firstPath, secondPath, rootDir, args)
+	} else if args.mode == "export" {
 		uassetPath := filepath.Join(parentDir, baseName+".uasset")
 		outPath := filepath.Join(outdir, baseName+"."+args.format)
 		processed = Export(uassetPath, outPath, args)
@@ -189,12 +995,34 @@ func processFile(filePath string, rootDir string, assetDir string, args *options
 	} else if args.mode == "dualsub" {
 		firstPath := filepath.Join(parentDir, baseName+".uasset")
 		outPath := filepath.Join(outdir, baseName+".uasset")
-		processed = Dualsub(firstPath, secondPath, outPath, args)
+		sidecarPath := core.GetSidecarPath(filepath.Join(args.sidecarDir, relPath, baseName+".uasset"))
+		processed = Dualsub(firstPath, secondPath, outPath, sidecarPath, args)
+	} else if args.mode == "multisub" {
+		paths := []string{filepath.Join(parentDir, baseName+".uasset")}
+		for _, path := range args.files[1:] {
+			if core.PathIsDir(path) {
+				path = filepath.Join(path, relPath, baseName+".uasset")
+			}
+			paths = append(paths, path)
+		}
+		outPath := filepath.Join(outdir, baseName+".uasset")
+		processed = Multisub(paths, outPath, args)
+	} else if args.mode == "unmerge" {
+		uassetPath := filepath.Join(parentDir, baseName+".uasset")
+		sidecarPath := core.GetSidecarPath(filepath.Join(args.sidecarDir, relPath, baseName+".uasset"))
+		processed = Unmerge(uassetPath, sidecarPath, relPath, args)
+	} else if args.mode == "patch-widget" {
+		uassetPath := filepath.Join(parentDir, baseName+".uasset")
+		outPath := filepath.Join(outdir, baseName+".uasset")
+		processed = PatchWidget(uassetPath, outPath, args)
+	} else if args.mode == "dump" {
+		uassetPath := filepath.Join(parentDir, baseName+".uasset")
+		outPath := filepath.Join(outdir, baseName+".json")
+		processed = Dump(uassetPath, outPath, args)
 	} else if args.mode == "resize" {
-		firstPath := filepath.Join(parentDir, baseName+".uasset")
+		uassetPath := filepath.Join(parentDir, baseName+".uasset")
 		outPath := filepath.Join(outdir, baseName+".uasset")
-		core.ResizeSubtitleWidget(firstPath, outPath, args.subtitleBoxWidth, args.subttleBoxHeight)
-		processed = 1
+		processed = Resize(uassetPath, outPath, args)
 	} else if args.mode == "test" {
 		uassetPath := filepath.Join(parentDir, baseName+".uasset")
 		newDataPath := filepath.Join(outdir, baseName+"."+args.format)
@@ -210,10 +1038,54 @@ func processFile(filePath string, rootDir string, assetDir string, args *options
 		}
 		processed = 1
 	}
+	core.EmitFileDone(filePath, processed, start)
 	return processed
 }
 
-func multiProcessFiles(filePath string, assetPath string, targetExt string, args *options) int {
+// Process a file, and record the error instead of exiting
+func tryProcessFile(filePath string, rootDir string, assetDir string, args *options) int {
+	processed := 0
+	err := core.Try(func() {
+		processed = processFile(filePath, rootDir, assetDir, args)
+	})
+	if err != nil {
+		args.failures.Add(filePath, err)
+	}
+	return processed
+}
+
+// Without --keep-going, remaining files are skipped after a failure.
+// Files that are being processed are still written.
+func isStopped(args *options) bool {
+	return !args.keepGoing && args.failures.Count() > 0
+}
+
+// Get input files that have the extension. A file path should have the extension.
+func collectFiles(filePath string, targetExt string) []string {
+	if !core.PathIsDir(filePath) {
+		_, _, ext := core.SplitFilePath(filePath)
+		if ext != targetExt {
+			core.Throw(fmt.Errorf("not %s. (%s)", targetExt, filePath))
+		}
+		return []string{filePath}
+	}
+	files := []string{}
+	err := filepath.WalkDir(filePath, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("error accessing path %q: %v", path, err)
+		}
+		if !d.IsDir() && filepath.Ext(path) == targetExt {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		core.Throw(err)
+	}
+	return files
+}
+
+func multiProcessFiles(filePath string, assetPath string, files []string, args *options) int {
 	fileCount := 0
 	fileChan := make(chan string, 128)
 	var wg sync.WaitGroup
@@ -225,44 +1097,202 @@ func multiProcessFiles(filePath string, assetPath string, targetExt string, args
 		go func() {
 			defer wg.Done()
 			for file := range fileChan {
-				func(file string) {
-					defer core.ErrorCheckWithMsg(fmt.Sprintf("Input path: %s\n", file))
-					processed := processFile(file, filePath, assetPath, args)
-					countMutex.Lock()
-					fileCount += processed
-					countMutex.Unlock()
-				}(file)
+				if isStopped(args) {
+					continue
+				}
+				processed := tryProcessFile(file, filePath, assetPath, args)
+				countMutex.Lock()
+				fileCount += processed
+				countMutex.Unlock()
 			}
 		}()
 	}
 
-	// Search files and send queues
-	err := filepath.WalkDir(filePath, func(path string, d os.DirEntry, err error) error {
+	// Send queues
+	for _, file := range files {
+		if isStopped(args) {
+			break
+		}
+		fileChan <- file
+	}
+	close(fileChan)
+	wg.Wait()
+	return fileCount
+}
+
+// Modification time and size of a file
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// Get states of files that have the extensions. Files in outdir are ignored.
+func scanFiles(root string, exts []string, outdir string, states map[string]fileState) {
+	filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
 		if err != nil {
-			return fmt.Errorf("error accessing path %q: %v", path, err)
+			return nil // files can be removed while saving
 		}
-		if !d.IsDir() {
-			ext := filepath.Ext(path)
-			if ext == targetExt {
-				fileChan <- path // Send file path to the channel
+		if d.IsDir() {
+			if path == outdir {
+				return filepath.SkipDir
 			}
+			return nil
+		}
+		if !slices.Contains(exts, filepath.Ext(path)) {
+			return nil
+		}
+		info, err := d.Info()
+		if err == nil {
+			states[path] = fileState{modTime: info.ModTime(), size: info.Size()}
 		}
 		return nil
 	})
+}
+
+// Process files again when they are changed.
+// Changed files are processed after they stay the same for a polling interval.
+func Watch(filePath string, assetPath string, targetExt string, args *options) {
+	rootDir := filePath
+	if !core.PathIsDir(filePath) {
+		rootDir = filepath.Dir(filePath)
+	}
+	outdir := core.GetFullPath(args.outdir)
+
+	// Dualsub mode also watches assets of the second language, and .uexp files of assets
+	watchSecond := args.mode == "dualsub"
+	exts := []string{targetExt}
+	if watchSecond {
+		exts = append(exts, ".uexp")
+	}
+	scanPath := func(path string, states map[string]fileState) {
+		scanFiles(path, exts, outdir, states)
+		if watchSecond && filepath.Ext(path) == ".uasset" {
+			scanFiles(core.RemoveExtension(path)+".uexp", exts, outdir, states)
+		}
+	}
+	scan := func() map[string]fileState {
+		states := map[string]fileState{}
+		scanPath(filePath, states)
+		if watchSecond {
+			scanPath(assetPath, states)
+		}
+		return states
+	}
+
+	// Get the input path of processFile for a changed file
+	toInputPath := func(path string) string {
+		if filepath.Ext(path) == ".uexp" {
+			path = core.RemoveExtension(path) + ".uasset"
+		}
+		if !watchSecond || path == filePath || strings.HasPrefix(path, rootDir+string(filepath.Separator)) {
+			return path
+		}
+		if !core.PathIsDir(assetPath) {
+			return filePath
+		}
+		relPath, err := filepath.Rel(assetPath, path)
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(filePath, relPath)
+	}
+
+	process := func(paths []string) {
+		inputs := []string{}
+		for _, path := range paths {
+			input := toInputPath(path)
+			if input == "" || !core.PathExists(input) {
+				core.Warn("no input file for the changed file. (%s)", path)
+				continue
+			}
+			if !slices.Contains(inputs, input) {
+				inputs = append(inputs, input)
+			}
+		}
+		slices.Sort(inputs)
+		for _, input := range inputs {
+			start := time.Now()
+			processed := 0
+			err := core.Try(func() {
+				processed = processFile(input, rootDir, assetPath, args)
+			})
+			if err != nil {
+				core.PrintError(err, input)
+			} else if processed == 0 {
+				core.Printf("No files processed... (%s)\n", input)
+			} else {
+				core.Printf("Done! processed %s in %v\n", input, time.Since(start))
+			}
+		}
+	}
+
+	// The first scan is the baseline. Files are processed only with --watch_initial.
+	states := scan()
+	if args.watchInitial {
+		initial := []string{}
+		for path := range states {
+			if filepath.Ext(path) == targetExt && toInputPath(path) == path {
+				initial = append(initial, path)
+			}
+		}
+		process(initial)
+	}
+
+	core.Printf("Watching %s... (Press Ctrl+C to stop)\n", filePath)
+	interval := time.Duration(args.watchInterval) * time.Millisecond
+	pending := []string{}
+	for {
+		time.Sleep(interval)
+		newStates := scan()
+		changed := []string{}
+		for path, state := range newStates {
+			if old, ok := states[path]; !ok || old != state {
+				changed = append(changed, path)
+			}
+		}
+		states = newStates
+
+		// Wait for the next poll when files are still being saved
+		ready := []string{}
+		for _, path := range pending {
+			if _, ok := states[path]; ok && !slices.Contains(changed, path) {
+				ready = append(ready, path)
+			}
+		}
+		pending = changed
+		if len(ready) > 0 {
+			process(ready)
+		}
+	}
+}
+
+// Run the web editor. The first path is edited, and the rest are shown as references.
+func Serve(args *options) {
+	editor := core.NewEditor(args.files[0], args.files[1:], args.outdir)
+	addr := net.JoinHostPort(args.host, strconv.Itoa(args.port))
+	core.Printf("Serving the editor on http://%s/ (Ctrl+C to stop)\n", addr)
+	err := http.ListenAndServe(addr, editor.Handler(addr))
 	if err != nil {
 		core.Throw(err)
 	}
+}
 
-	close(fileChan)
-	wg.Wait()
-	return fileCount
+// Run the terminal editor. Edited assets are saved as new files in outdir.
+func EditInTerminal(args *options) {
+	filePath := args.files[0]
+	if filepath.Ext(filePath) != ".uasset" || core.PathIsDir(filePath) {
+		core.Throw(fmt.Errorf("you should specify a .uasset file for edit mode. (%s)", filePath))
+	}
+	tui := core.NewTui(filePath, filepath.Join(args.outdir, filepath.Base(filePath)))
+	tui.Run()
+	if tui.IsModified() {
+		core.Warn("quit without saving changes. (%s)", filePath)
+	}
 }
 
 func main() {
 	start := time.Now()
 
-	fmt.Printf("ff7r-text-tool v%s by Matyalatte\n", TOOL_VERSION)
-
 	// Remove time info from log
 	log.SetFlags(log.Flags() &^ (log.Ldate | log.Ltime))
 
@@ -270,37 +1300,109 @@ func main() {
 	defer core.ErrorCheck()
 
 	args := argparse()
+	startEvent := core.ProgressEvent{
+		Event:   "start",
+		Mode:    args.mode,
+		Version: TOOL_VERSION,
+		Inputs:  args.files,
+		Workers: args.numWorkers,
+	}
 	filePath := args.files[0]
 	assetPath := filePath
-	if args.mode == "import" || args.mode == "dualsub" {
+	if args.mode == "import" || args.mode == "dualsub" || args.mode == "multisub" || args.mode == "glossary" || args.mode == "mt" {
+		assetPath = args.files[1]
+	} else if args.mode == "convert-script" && len(args.files) > 1 {
 		assetPath = args.files[1]
 	}
 
 	targetExt := ".uasset"
 	if args.mode == "import" {
 		targetExt = "." + args.format // .csv or .json
+	} else if args.mode == "lint" || args.mode == "search" || args.mode == "stats" || args.mode == "glyphs" || args.mode == "charset" {
+		targetExt = "." + args.inputFormat
+	}
+
+	if args.watch || args.mode == "serve" || args.mode == "edit" || args.mode == "lookup" {
+		// No input files to count
+		core.EmitProgress(startEvent)
+	}
+
+	if args.watch {
+		Watch(filePath, assetPath, targetExt, args)
+		return
+	}
+
+	if args.mode == "serve" {
+		Serve(args)
+		return
+	}
+
+	if args.mode == "edit" {
+		EditInTerminal(args)
+		return
 	}
 
 	fileCount := 0
 
-	if core.PathIsDir(filePath) {
-		fileCount = multiProcessFiles(filePath, assetPath, targetExt, args)
+	if args.mode == "lookup" {
+		fileCount = Lookup(filePath, args)
 	} else {
-		parentDir, _, ext := core.SplitFilePath(filePath)
-		if ext != targetExt {
-			core.Throw(fmt.Errorf("not %s. (%s)", targetExt, filePath))
+		// Collect files first to emit the total
+		files := collectFiles(filePath, targetExt)
+		total := len(files)
+		startEvent.Total = &total
+		core.EmitProgress(startEvent)
+		if core.PathIsDir(filePath) {
+			fileCount = multiProcessFiles(filePath, assetPath, files, args)
+		} else {
+			parentDir, _, _ := core.SplitFilePath(filePath)
+			fileCount = tryProcessFile(filePath, parentDir, assetPath, args)
 		}
-		processed := processFile(filePath, parentDir, assetPath, args)
-		fileCount += processed
+	}
+
+	failed := args.failures.Count()
+	if failed > 0 && !args.keepGoing {
+		// Reports are not saved because some files are not processed
+		args.failures.WriteAsText(os.Stderr)
+		core.EmitSummary(fileCount, failed, start)
+		os.Exit(args.failures.ExitCode())
+	}
+
+	if args.mode == "tags" {
+		outPath := filepath.Join(args.outdir, "tags.json")
+		core.SaveAsJson(outPath, args.tagCatalog.GetTags())
+	} else if args.mode == "lint" {
+		SaveLintReport(args)
+	} else if args.mode == "glossary" {
+		SaveGlossaryReport(args)
+	} else if args.mode == "search" {
+		SaveSearchResult(args)
+	} else if args.mode == "index" {
+		SaveIndex(args)
+	} else if args.mode == "stats" {
+		SaveStatsReport(args)
+	} else if args.mode == "glyphs" {
+		SaveGlyphReport(args)
+	} else if args.mode == "charset" {
+		SaveCharset(args)
 	}
 
 	// Print result
+	core.EmitSummary(fileCount, failed, start)
+	if failed > 0 {
+		args.failures.WriteAsText(os.Stderr)
+	}
 	duration := time.Since(start)
-	if fileCount == 0 {
-		fmt.Printf("No files processed...\n")
+	if args.mode == "lookup" && fileCount == 1 {
+		core.Printf("Done! found 1 hit in %v\n", duration)
+	} else if args.mode == "lookup" {
+		core.Printf("Done! found %d hits in %v\n", fileCount, duration)
+	} else if fileCount == 0 {
+		core.Printf("No files processed...\n")
 	} else if fileCount == 1 {
-		fmt.Printf("Done! processed 1 file in %v\n", duration)
+		core.Printf("Done! processed 1 file in %v\n", duration)
 	} else {
-		fmt.Printf("Done! processed %d files in %v\n", fileCount, duration)
+		core.Printf("Done! processed %d files in %v\n", fileCount, duration)
 	}
+	os.Exit(args.failures.ExitCode())
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	core "ff7r-text-tool/core"
+)
+
+func TestKeepGoing(t *testing.T) {
+	tests := []struct {
+		keepGoing bool
+		failed    bool
+		stopped   bool
+		exitCode  int
+	}{
+		{false, false, false, core.EXIT_OK},
+		{true, false, false, core.EXIT_OK},
+		{false, true, true, core.EXIT_FAILED},
+		{true, true, false, core.EXIT_FAILED}, // remaining files are processed but the exit code is the same
+	}
+	dir := t.TempDir()
+	for _, test := range tests {
+		args := &options{mode: "export", format: "json", outdir: dir, keepGoing: test.keepGoing, failures: core.NewFailureReport()}
+		if test.failed {
+			// The error is recorded instead of exiting
+			tryProcessFile(filepath.Join(dir, "Missing.uasset"), dir, "", args)
+		}
+		if isStopped(args) != test.stopped || args.failures.ExitCode() != test.exitCode {
+			t.Errorf("keepGoing=%v, failed=%v: got stopped=%v, exit code %d",
+				test.keepGoing, test.failed, isStopped(args), args.failures.ExitCode())
+		}
+	}
+}